@@ -0,0 +1,208 @@
+// Package client is a Go SDK for the gorest API: typed resource access with context support, retries with backoff
+// on idempotent requests and server errors returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error An error response of the server, mirroring the server's ErrorHttp body.
+type Error struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Error Implements error.
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("gorest: %d %s (request %s)", e.Status, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("gorest: %d %s", e.Status, e.Message)
+}
+
+// Options Settings of a Client. Zero values select the defaults.
+type Options struct {
+	// HTTPClient sends the requests, http.DefaultClient when nil. A client Timeout also ends Watch streams,
+	// which are then resumed.
+	HTTPClient *http.Client
+	// APIKey is sent as X-API-Key, Token as a Bearer token.
+	APIKey string
+	Token  string
+	// Tenant is sent as X-Tenant to select a tenant store.
+	Tenant string
+	// Retries is how often idempotent requests are retried after network errors and 429, 502, 503 and 504
+	// responses; 3 when zero, none when negative.
+	Retries int
+	// Backoff is the first retry delay, doubled on every attempt up to MaxBackoff; 100ms and 5s when zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client A gorest API client, safe for concurrent use.
+type Client struct {
+	base *url.URL
+	opts Options
+}
+
+// New Returns a client of the server at baseURL, e.g. http://localhost:8181.
+func New(baseURL string, opts Options) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("client: %q is not an http(s) URL", baseURL)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Second
+	}
+	return &Client{base: base, opts: opts}, nil
+}
+
+// retryable Statuses worth retrying: rate limited or the server or a proxy is temporarily unavailable.
+func retryable(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// request Builds a request of path, relative to the API root, carrying the client's credentials.
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Request, error) {
+	u := *c.base
+	u.Path += "/api/" + path
+	u.RawQuery = query.Encode()
+
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+	}
+	if c.opts.APIKey != "" {
+		req.Header.Set("X-API-Key", c.opts.APIKey)
+	}
+	if c.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+	if c.opts.Tenant != "" {
+		req.Header.Set("X-Tenant", c.opts.Tenant)
+	}
+	return req, nil
+}
+
+// do Sends the request, retrying idempotent methods, and decodes a successful body into out when not nil.
+// Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+	retries := c.opts.Retries
+	if method == http.MethodPost || method == http.MethodPatch || retries < 0 {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := c.request(ctx, method, path, query, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.opts.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= retries {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if retryable(resp.StatusCode) && attempt < retries {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := c.wait(ctx, attempt, resp); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return resp, decode(resp, out)
+	}
+}
+
+// wait Sleeps before retry attempt+1: exponential backoff with jitter, or the server's Retry-After if longer.
+func (c *Client) wait(ctx context.Context, attempt int, resp *http.Response) error {
+	d := c.opts.Backoff << attempt
+	if d > c.opts.MaxBackoff || d <= 0 {
+		d = c.opts.MaxBackoff
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(s)*time.Second > d {
+			d = time.Duration(s) * time.Second
+		}
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// decode Closes resp after decoding its body into out, or into an *Error returned for error statuses.
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		e := &Error{}
+		if json.Unmarshal(b, e) != nil || e.Status == 0 {
+			e = &Error{Status: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+		}
+		if e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
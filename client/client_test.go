@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/gorilla/mux"
+)
+
+type hero struct {
+	Name     string `json:"name"`
+	Lastname string `json:"lastname,omitempty"`
+	Age      int    `json:"age,omitempty"`
+}
+
+// server Serves the resource routes of a fresh store.
+func server(t *testing.T) (*httptest.Server, *Client) {
+	rh := handlers.CreateHandler(make(map[string]map[string]interface{}))
+	router := mux.NewRouter().StrictSlash(true)
+	api := router.PathPrefix("/api/").Subrouter()
+	api.HandleFunc("/resources/events", rh.GetEventsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/resources/{id}", rh.GetResourceHandler).Methods(http.MethodGet)
+	api.HandleFunc("/resources", rh.GetResourcesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/resources", rh.CreateResourceHandler).Methods(http.MethodPost)
+	api.HandleFunc("/resources/{id}", rh.UpdateResourceHandler).Methods(http.MethodPut)
+	api.HandleFunc("/resources/{id}", rh.PatchResourceHandler).Methods(http.MethodPatch)
+	api.HandleFunc("/resources/{id}", rh.DeleteResourceHandler).Methods(http.MethodDelete)
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		rh.Close()
+		srv.Close()
+	})
+
+	c, err := New(srv.URL, Options{Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, c
+}
+
+// TestCollection CRUD round trip with typed documents.
+func TestCollection(t *testing.T) {
+	ctx := context.Background()
+	_, c := server(t)
+	heroes := Resources[hero](c)
+
+	created, err := heroes.Create(ctx, hero{Name: "Bruce", Lastname: "Wayne", Age: 30})
+	if err != nil || created.ID == "" || created.Doc.Name != "Bruce" {
+		t.Fatalf("Create: got %+v, %v", created, err)
+	}
+	got, err := heroes.Get(ctx, created.ID)
+	if err != nil || got != created.Doc {
+		t.Errorf("Get: got %+v, %v want %+v", got, err, created.Doc)
+	}
+	replaced, err := heroes.Replace(ctx, created.ID, hero{Name: "Clark", Lastname: "Kent"})
+	if err != nil || replaced.Name != "Clark" || replaced.Age != 0 {
+		t.Errorf("Replace: got %+v, %v", replaced, err)
+	}
+	patched, err := heroes.Patch(ctx, created.ID, map[string]interface{}{"age": 35, "lastname": nil})
+	if err != nil || patched != (hero{Name: "Clark", Age: 35}) {
+		t.Errorf("Patch: got %+v, %v", patched, err)
+	}
+	if err := heroes.Delete(ctx, created.ID); err != nil {
+		t.Errorf("Delete: got %v", err)
+	}
+
+	_, err = heroes.Get(ctx, created.ID)
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest || e.Message == "" {
+		t.Errorf("Get deleted: got %v want *Error with status 400", err)
+	}
+}
+
+// TestCollection_List Filters and pages through every resource.
+func TestCollection_List(t *testing.T) {
+	ctx := context.Background()
+	_, c := server(t)
+	heroes := Resources[hero](c)
+	for age := 1; age <= 7; age++ {
+		if _, err := heroes.Create(ctx, hero{Name: "Hero", Age: age}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want int
+	}{
+		{name: "List - All Success", want: 7},
+		{name: "List - Pages Success", opts: ListOptions{PageSize: 3}, want: 7},
+		{name: "List - Exact Pages Success", opts: ListOptions{PageSize: 7}, want: 7},
+		{name: "List - Filter Success", opts: ListOptions{Filter: "age > 4", PageSize: 2}, want: 3},
+		{name: "List - No Match Success", opts: ListOptions{Filter: "age > 40"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := heroes.All(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != tt.want {
+				t.Errorf("got %d resources want %d", len(all), tt.want)
+			}
+			seen := map[string]bool{}
+			for _, r := range all {
+				if seen[r.ID] || r.Doc.Name != "Hero" {
+					t.Errorf("got duplicate or empty resource %+v", r)
+				}
+				seen[r.ID] = true
+			}
+		})
+	}
+
+	if _, err := heroes.All(ctx, ListOptions{Filter: "age >"}); err == nil {
+		t.Errorf("got nil error for invalid filter")
+	}
+}
+
+// TestClient_Retries Idempotent requests are retried on temporary failures; creates are not.
+func TestClient_Retries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"status":503,"message":"busy"}`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"name":"Bruce"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		retries int
+		call    func(col *Collection[hero]) error
+		calls   int32
+		err     bool
+	}{
+		{name: "Retries - Get Success", call: func(col *Collection[hero]) error {
+			_, err := col.Get(context.Background(), "1")
+			return err
+		}, calls: 3},
+		{name: "Retries - Exhausted Failure", retries: 1, call: func(col *Collection[hero]) error {
+			_, err := col.Get(context.Background(), "1")
+			return err
+		}, calls: 2, err: true},
+		{name: "Retries - Create Not Retried Failure", call: func(col *Collection[hero]) error {
+			_, err := col.Create(context.Background(), hero{Name: "Bruce"})
+			return err
+		}, calls: 1, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			c, _ := New(srv.URL, Options{Retries: tt.retries, Backoff: time.Millisecond})
+			err := tt.call(Resources[hero](c))
+			if tt.err != (err != nil) {
+				t.Errorf("got error %v want error %v", err, tt.err)
+			}
+			var e *Error
+			if err != nil && (!errors.As(err, &e) || e.Status != 503 || e.Message != "busy") {
+				t.Errorf("got %v want *Error 503 busy", err)
+			}
+			if n := atomic.LoadInt32(&calls); n != tt.calls {
+				t.Errorf("got %d calls want %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+// TestCollection_Watch Changes arrive as typed events.
+func TestCollection_Watch(t *testing.T) {
+	_, c := server(t)
+	heroes := Resources[hero](c)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := heroes.Watch(ctx, WatchOptions{Document: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := heroes.Create(ctx, hero{Name: "Bruce"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heroes.Patch(ctx, created.ID, map[string]int{"age": 30})
+
+	for _, want := range []string{EventCreated, EventUpdated} {
+		e := <-events
+		if e.Type != want || e.ID != created.ID || e.Seq == 0 || e.Document == nil || e.Document.Name != "Bruce" {
+			t.Errorf("got %+v want %s of %s", e, want, created.ID)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+// TestCollection_Watch_Refused A reconnect resumes from the position the server reported, and a refused one ends
+// the watch with an EventError.
+func TestCollection_Watch_Refused(t *testing.T) {
+	var calls int32
+	var resumed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("id: 7\n\n"))
+			return
+		}
+		resumed = r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"status":403,"message":"denied"}`))
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL, Options{Backoff: time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := Resources[hero](c).Watch(ctx, WatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Event[hero]
+	for e := range events {
+		got = append(got, e)
+	}
+	var e *Error
+	if len(got) != 1 || got[0].Type != EventError || !errors.As(got[0].Err, &e) || e.Status != 403 {
+		t.Errorf("got %+v want a single %s with a 403", got, EventError)
+	}
+	if resumed != "7" {
+		t.Errorf("got Last-Event-ID %q want %q", resumed, "7")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("got %d calls want %d", n, 2)
+	}
+}
+
+// TestNew Base URLs must be absolute http(s) URLs.
+func TestNew(t *testing.T) {
+	for _, u := range []string{"localhost:8181", "ftp://host", "://"} {
+		if _, err := New(u, Options{}); err == nil {
+			t.Errorf("%q: got nil error", u)
+		}
+	}
+}
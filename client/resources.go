@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Resource A document with its id.
+type Resource[T any] struct {
+	ID  string
+	Doc T
+}
+
+// Collection Typed access to a resource collection, decoding documents into T.
+type Collection[T any] struct {
+	c    *Client
+	name string
+}
+
+// Resources Returns the resources collection of c with documents decoded into T, e.g. a struct with json tags or
+// map[string]interface{}.
+func Resources[T any](c *Client) *Collection[T] {
+	return &Collection[T]{c: c, name: "resources"}
+}
+
+// Create POST /api/resources. Not retried, since a lost response would create the document twice.
+func (col *Collection[T]) Create(ctx context.Context, doc T) (Resource[T], error) {
+	var res Resource[T]
+	resp, err := col.c.do(ctx, http.MethodPost, col.name, nil, doc, &res.Doc)
+	if err != nil {
+		return res, err
+	}
+	if res.ID = path.Base(resp.Header.Get("Location")); res.ID == "." || res.ID == "/" {
+		return res, errors.New("client: created resource has no Location")
+	}
+	return res, nil
+}
+
+// Get GET /api/resources/{id}
+func (col *Collection[T]) Get(ctx context.Context, id string) (T, error) {
+	var doc T
+	_, err := col.c.do(ctx, http.MethodGet, col.name+"/"+url.PathEscape(id), nil, nil, &doc)
+	return doc, err
+}
+
+// Replace PUT /api/resources/{id}
+func (col *Collection[T]) Replace(ctx context.Context, id string, doc T) (T, error) {
+	var out T
+	_, err := col.c.do(ctx, http.MethodPut, col.name+"/"+url.PathEscape(id), nil, doc, &out)
+	return out, err
+}
+
+// Patch PATCH /api/resources/{id} with patch as a JSON merge patch: its fields replace those of the document and
+// null fields are removed. Pass a map or a struct with omitempty fields. Not retried.
+func (col *Collection[T]) Patch(ctx context.Context, id string, patch interface{}) (T, error) {
+	var out T
+	_, err := col.c.do(ctx, http.MethodPatch, col.name+"/"+url.PathEscape(id), nil, patch, &out)
+	return out, err
+}
+
+// Delete DELETE /api/resources/{id}
+func (col *Collection[T]) Delete(ctx context.Context, id string) error {
+	_, err := col.c.do(ctx, http.MethodDelete, col.name+"/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ListOptions Selects the resources returned by List.
+type ListOptions struct {
+	// Filter is a server-side filter expression, e.g. `name == "Bruce" and age >= 30`.
+	Filter string
+	// PageSize is how many resources are fetched per request; all at once when zero.
+	PageSize int
+}
+
+// List Returns an iterator over the resources matching opts, fetching pages from the server as it advances.
+// Paged results are ordered by id.
+func (col *Collection[T]) List(ctx context.Context, opts ListOptions) *Iterator[T] {
+	return &Iterator[T]{col: col, ctx: ctx, opts: opts}
+}
+
+// All Returns every resource matching opts.
+func (col *Collection[T]) All(ctx context.Context, opts ListOptions) ([]Resource[T], error) {
+	var all []Resource[T]
+	it := col.List(ctx, opts)
+	for it.Next() {
+		all = append(all, it.Resource())
+	}
+	return all, it.Err()
+}
+
+// Iterator Walks the pages of a List:
+//
+//	it := col.List(ctx, client.ListOptions{PageSize: 100})
+//	for it.Next() {
+//	    r := it.Resource()
+//	}
+//	if err := it.Err(); err != nil {
+//	    ...
+//	}
+type Iterator[T any] struct {
+	col  *Collection[T]
+	ctx  context.Context
+	opts ListOptions
+
+	page  []Resource[T]
+	cur   Resource[T]
+	after string
+	last  bool
+	err   error
+}
+
+// Next Advances to the next resource, fetching the next page when needed. It returns false at the end or on error.
+func (it *Iterator[T]) Next() bool {
+	for len(it.page) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		it.err = it.fetch()
+	}
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Resource Returns the current resource.
+func (it *Iterator[T]) Resource() Resource[T] {
+	return it.cur
+}
+
+// Err Returns the error that ended the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// fetch Loads the page after it.after.
+func (it *Iterator[T]) fetch() error {
+	q := url.Values{}
+	if it.opts.Filter != "" {
+		q.Set("filter", it.opts.Filter)
+	}
+	if it.opts.PageSize > 0 {
+		q.Set("limit", strconv.Itoa(it.opts.PageSize))
+	}
+	if it.after != "" {
+		q.Set("after", it.after)
+	}
+
+	docs := map[string]json.RawMessage{}
+	resp, err := it.col.c.do(it.ctx, http.MethodGet, it.col.name, q, nil, &docs)
+	if err != nil {
+		return err
+	}
+	it.last = it.opts.PageSize == 0 || resp.Header.Get("Link") == ""
+
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r := Resource[T]{ID: id}
+		if err := json.Unmarshal(docs[id], &r.Doc); err != nil {
+			return err
+		}
+		it.page = append(it.page, r)
+	}
+	if len(ids) > 0 {
+		it.after = ids[len(ids)-1]
+	} else {
+		it.last = true
+	}
+	return nil
+}
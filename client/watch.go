@@ -0,0 +1,172 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types sent by Watch.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+	// EventReset means events were missed, e.g. while reconnecting, and the collection should be re-read.
+	EventReset = "reset"
+	// EventError ends a watch the server refused to resume, e.g. once credentials were revoked; Err holds why.
+	EventError = "error"
+)
+
+// Event A change of a resource.
+type Event[T any] struct {
+	Seq     uint64    `json:"-"`
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// Document is the changed document, set when watching with WatchOptions.Document.
+	Document *T `json:"document,omitempty"`
+	// Err is the error ending the watch, set on EventError.
+	Err error `json:"-"`
+}
+
+// WatchOptions Selects what Watch receives.
+type WatchOptions struct {
+	// Document includes the changed document in every event.
+	Document bool
+	// After resumes after the event with this sequence number. Zero starts at the current position: only changes
+	// made after the first connection are received.
+	After uint64
+}
+
+// Watch GET /api/resources/events. Streams changes until ctx is done, then closes the channel. Dropped streams are
+// resumed from the last received event or position, with backoff; a reset event reports changes that could not be
+// replayed. The first connection's error is returned. Later ones are retried until ctx is done, except refusals
+// (401, 403 and 404), which are sent as a final EventError before the channel is closed.
+func (col *Collection[T]) Watch(ctx context.Context, opts WatchOptions) (<-chan Event[T], error) {
+	resp, err := col.stream(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event[T])
+	go func() {
+		defer close(ch)
+		for attempt := 0; ; {
+			if resp != nil {
+				attempt = 0
+				opts.After = col.read(ctx, resp, opts.After, ch)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if col.c.wait(ctx, attempt, nil) != nil {
+				return
+			}
+			attempt++
+			var err error
+			if resp, err = col.stream(ctx, opts); refused(err) {
+				select {
+				case ch <- Event[T]{Type: EventError, Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// refused Reports whether err is a response of the server that retrying will not change.
+func refused(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden || e.Status == http.StatusNotFound
+}
+
+// stream Opens the event stream.
+func (col *Collection[T]) stream(ctx context.Context, opts WatchOptions) (*http.Response, error) {
+	q := url.Values{}
+	if opts.Document {
+		q.Set("document", "true")
+	}
+	req, err := col.c.request(ctx, http.MethodGet, col.name+"/events", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if opts.After > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(opts.After, 10))
+	}
+
+	resp, err := col.c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := decode(resp, nil)
+		if err == nil {
+			err = fmt.Errorf("client: unexpected event stream status %d", resp.StatusCode)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// read Sends the events of resp to ch until the stream ends, returning the sequence number of the last one or of
+// the position the server reported without an event.
+func (col *Collection[T]) read(ctx context.Context, resp *http.Response, last uint64, ch chan<- Event[T]) uint64 {
+	defer resp.Body.Close()
+
+	var id, typ, data string
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "id":
+				id = value
+			case "event":
+				typ = value
+			case "data":
+				data = value
+			}
+			continue
+		}
+
+		// A blank line dispatches the event; comments such as heartbeats carry no data, and an id alone moves the
+		// position without an event.
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err == nil && data == "" {
+			last = n
+		}
+		if data != "" {
+			e := Event[T]{Type: typ}
+			if typ != EventReset && json.Unmarshal([]byte(data), &e) != nil {
+				return last
+			}
+			if err == nil {
+				e.Seq, last = n, n
+			}
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return last
+			}
+		}
+		id, typ, data = "", "", ""
+	}
+	return last
+}
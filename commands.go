@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/angarcia/gorest/client"
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+)
+
+// document A resource document as handled by the client commands.
+type document = map[string]interface{}
+
+// clientFlags Returns the connection and output flags shared by the client commands, plus extra.
+func clientFlags(extra ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{Name: "url", Aliases: []string{"u"}, Usage: "server URL", Value: "http://localhost:8181", EnvVars: []string{"GOREST_URL"}},
+		&cli.StringFlag{Name: "api-key", Usage: "API key sent as X-API-Key", EnvVars: []string{"GOREST_API_KEY"}},
+		&cli.StringFlag{Name: "token", Usage: "bearer token", EnvVars: []string{"GOREST_TOKEN"}},
+		&cli.StringFlag{Name: "tenant", Usage: "tenant sent as X-Tenant", EnvVars: []string{"GOREST_TENANT"}},
+		&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output format: json, yaml or table", Value: "json", EnvVars: []string{"GOREST_OUTPUT"}},
+	}, extra...)
+}
+
+// fileFlag Selects the file a body is read from.
+func fileFlag() cli.Flag {
+	return &cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "JSON or YAML file to read, - for stdin", Value: "-"}
+}
+
+// clientCommands Returns the commands talking to a server.
+func clientCommands() []*cli.Command {
+	return []*cli.Command{
+		{Name: "get", Usage: "print a resource", ArgsUsage: "ID", Flags: clientFlags(), Action: withClient(getAction)},
+		{Name: "list", Usage: "print the resources", Flags: clientFlags(
+			&cli.StringFlag{Name: "filter", Usage: `filter expression, e.g. 'name == "Bruce" and age >= 30'`},
+			&cli.IntFlag{Name: "page-size", Usage: "resources fetched per request (0 fetches all at once)", Value: 100},
+		), Action: withClient(listAction)},
+		{Name: "create", Usage: "create a resource from a file or stdin", Flags: clientFlags(fileFlag()), Action: withClient(createAction)},
+		{Name: "update", Usage: "replace a resource with a file or stdin", ArgsUsage: "ID", Flags: clientFlags(fileFlag()), Action: withClient(updateAction)},
+		{Name: "patch", Usage: "apply a JSON merge patch from a file or stdin", ArgsUsage: "ID", Flags: clientFlags(fileFlag()), Action: withClient(patchAction)},
+		{Name: "delete", Usage: "delete resources", ArgsUsage: "ID...", Flags: clientFlags(), Action: withClient(deleteAction)},
+		{Name: "import", Usage: "create every document of an array, or of an object keyed by id as written by export",
+			Flags: clientFlags(fileFlag()), Action: withClient(importAction)},
+		{Name: "export", Usage: "print every resource keyed by id", Flags: clientFlags(
+			&cli.StringFlag{Name: "filter", Usage: "filter expression"},
+		), Action: withClient(exportAction)},
+		{Name: "watch", Usage: "print resource changes until interrupted", Flags: clientFlags(
+			&cli.BoolFlag{Name: "document", Usage: "include changed documents"},
+		), Action: withClient(watchAction)},
+	}
+}
+
+// command State of a running client command.
+type command struct {
+	*cli.Context
+	ctx       context.Context
+	resources *client.Collection[document]
+	out       *printer
+}
+
+// withClient Adapts a client command action, connecting to the server selected by the flags.
+func withClient(action func(cmd *command) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		out, err := newPrinter(c.App.Writer, c.String("output"))
+		if err != nil {
+			return err
+		}
+		cl, err := client.New(c.String("url"), client.Options{
+			APIKey: c.String("api-key"),
+			Token:  c.String("token"),
+			Tenant: c.String("tenant"),
+		})
+		if err != nil {
+			return err
+		}
+		return action(&command{Context: c, ctx: c.Context, resources: client.Resources[document](cl), out: out})
+	}
+}
+
+// id Returns the single ID argument.
+func (cmd *command) id() (string, error) {
+	if cmd.NArg() != 1 {
+		return "", fmt.Errorf("%s: expected one ID argument", cmd.Command.Name)
+	}
+	return cmd.Args().First(), nil
+}
+
+// read Returns the JSON or YAML value in the --file file, or stdin.
+func (cmd *command) read(v interface{}) error {
+	var r io.Reader = cmd.App.Reader
+	if name := cmd.String("file"); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, v)
+}
+
+func getAction(cmd *command) error {
+	id, err := cmd.id()
+	if err != nil {
+		return err
+	}
+	doc, err := cmd.resources.Get(cmd.ctx, id)
+	if err != nil {
+		return err
+	}
+	return cmd.out.resource(id, doc)
+}
+
+func listAction(cmd *command) error {
+	all, err := cmd.resources.All(cmd.ctx, client.ListOptions{Filter: cmd.String("filter"), PageSize: cmd.Int("page-size")})
+	if err != nil {
+		return err
+	}
+	return cmd.out.resources(all)
+}
+
+func createAction(cmd *command) error {
+	var doc document
+	if err := cmd.read(&doc); err != nil {
+		return err
+	}
+	res, err := cmd.resources.Create(cmd.ctx, doc)
+	if err != nil {
+		return err
+	}
+	return cmd.out.created([]client.Resource[document]{res}, false)
+}
+
+func updateAction(cmd *command) error {
+	id, err := cmd.id()
+	if err != nil {
+		return err
+	}
+	var doc document
+	if err := cmd.read(&doc); err != nil {
+		return err
+	}
+	if doc, err = cmd.resources.Replace(cmd.ctx, id, doc); err != nil {
+		return err
+	}
+	return cmd.out.resource(id, doc)
+}
+
+func patchAction(cmd *command) error {
+	id, err := cmd.id()
+	if err != nil {
+		return err
+	}
+	var patch document
+	if err := cmd.read(&patch); err != nil {
+		return err
+	}
+	doc, err := cmd.resources.Patch(cmd.ctx, id, patch)
+	if err != nil {
+		return err
+	}
+	return cmd.out.resource(id, doc)
+}
+
+func deleteAction(cmd *command) error {
+	if cmd.NArg() == 0 {
+		return errors.New("delete: expected at least one ID argument")
+	}
+	for _, id := range cmd.Args().Slice() {
+		if err := cmd.resources.Delete(cmd.ctx, id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func importAction(cmd *command) error {
+	var in interface{}
+	if err := cmd.read(&in); err != nil {
+		return err
+	}
+	var docs []document
+	switch v := in.(type) {
+	case []interface{}:
+		for n, d := range v {
+			doc, ok := d.(document)
+			if !ok {
+				return fmt.Errorf("import: element %d is not an object", n)
+			}
+			docs = append(docs, doc)
+		}
+	case document:
+		for _, id := range sortedKeys(v) {
+			doc, ok := v[id].(document)
+			if !ok {
+				return fmt.Errorf("import: %s is not an object", id)
+			}
+			docs = append(docs, doc)
+		}
+	default:
+		return errors.New("import: expected an array or an object of documents")
+	}
+
+	var created []client.Resource[document]
+	for _, doc := range docs {
+		res, err := cmd.resources.Create(cmd.ctx, doc)
+		if err != nil {
+			// Report what was imported before the failure so the rest can be retried.
+			cmd.out.created(created, true)
+			return fmt.Errorf("import: %d of %d created: %w", len(created), len(docs), err)
+		}
+		created = append(created, res)
+	}
+	return cmd.out.created(created, true)
+}
+
+func exportAction(cmd *command) error {
+	all, err := cmd.resources.All(cmd.ctx, client.ListOptions{Filter: cmd.String("filter"), PageSize: 100})
+	if err != nil {
+		return err
+	}
+	return cmd.out.resources(all)
+}
+
+func watchAction(cmd *command) error {
+	ctx, stop := signal.NotifyContext(cmd.ctx, os.Interrupt)
+	defer stop()
+
+	events, err := cmd.resources.Watch(ctx, client.WatchOptions{Document: cmd.Bool("document")})
+	if err != nil {
+		return err
+	}
+	for e := range events {
+		if e.Type == client.EventError {
+			return e.Err
+		}
+		if err := cmd.out.event(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printer Writes command output as JSON, YAML or a table.
+type printer struct {
+	w      io.Writer
+	format string
+	// header is set once the table header of a stream of events was written.
+	header bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "json", "yaml", "table":
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use json, yaml or table", format)
+}
+
+// value Writes v as indented JSON or as YAML.
+func (p *printer) value(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if p.format == "yaml" {
+		if b, err = yaml.JSONToYAML(b); err != nil {
+			return err
+		}
+		_, err = p.w.Write(b)
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
+
+// resource Writes one document.
+func (p *printer) resource(id string, doc document) error {
+	if p.format == "table" {
+		return p.resources([]client.Resource[document]{{ID: id, Doc: doc}})
+	}
+	return p.value(doc)
+}
+
+// resources Writes documents keyed by id, the shape of GET /api/resources, or one table row each.
+func (p *printer) resources(rs []client.Resource[document]) error {
+	if p.format != "table" {
+		docs := make(map[string]document, len(rs))
+		for _, r := range rs {
+			docs[r.ID] = r.Doc
+		}
+		return p.value(docs)
+	}
+
+	fields := map[string]bool{}
+	for _, r := range rs {
+		for k := range r.Doc {
+			fields[k] = true
+		}
+	}
+	cols := sortedKeys(fields)
+	rows := [][]string{append([]string{"ID"}, upper(cols)...)}
+	for _, r := range rs {
+		row := []string{r.ID}
+		for _, col := range cols {
+			row = append(row, cell(r.Doc[col]))
+		}
+		rows = append(rows, row)
+	}
+	return table(p.w, rows)
+}
+
+// created Writes the ids and documents of created resources, as an array for import and one object for create.
+func (p *printer) created(rs []client.Resource[document], array bool) error {
+	if p.format == "table" {
+		return p.resources(rs)
+	}
+	out := make([]map[string]interface{}, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, map[string]interface{}{"id": r.ID, "document": r.Doc})
+	}
+	if !array && len(out) == 1 {
+		return p.value(out[0])
+	}
+	return p.value(out)
+}
+
+// event Writes one change: a JSON line, a YAML document or a table row.
+func (p *printer) event(e client.Event[document]) error {
+	switch p.format {
+	case "json":
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case "yaml":
+		fmt.Fprintln(p.w, "---")
+		return p.value(e)
+	}
+
+	// Rows are printed as they arrive, so columns have fixed widths instead of being aligned by a tabwriter.
+	if !p.header {
+		p.header = true
+		fmt.Fprintf(p.w, "%-8s %-24s %-8s %-36s %s\n", "SEQ", "TIME", "TYPE", "ID", "VERSION")
+	}
+	if e.Type == client.EventReset {
+		_, err := fmt.Fprintf(p.w, "%-8s %-24s %-8s %-36s %s\n", "-", "-", e.Type, "-", "-")
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "%-8d %-24s %-8s %-36s %d\n", e.Seq, e.Time.UTC().Format("2006-01-02T15:04:05.000Z"), e.Type, e.ID, e.Version)
+	return err
+}
+
+// cell Formats a field for a table: strings as they are, anything else as compact JSON.
+func cell(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func upper(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strings.ToUpper(s)
+	}
+	return out
+}
+
+// table Writes rows as aligned columns.
+func table(w io.Writer, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// sortedKeys Returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runCLI Runs the gorest command line with args against url until ctx is done, with stdin as input, returning its
+// output.
+func runCLI(ctx context.Context, t *testing.T, url, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	app := newApp()
+	app.Reader = strings.NewReader(stdin)
+	app.Writer = &out
+	app.ExitErrHandler = func(*cli.Context, error) {}
+	err := app.RunContext(ctx, append([]string{"gorest", args[0], "--url", url}, args[1:]...))
+	return out.String(), err
+}
+
+// seed Creates docs on the server at url, returning their ids in order.
+func seed(t *testing.T, url string, docs ...string) []string {
+	t.Helper()
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		resp, err := http.Post(url+"/api/resources", "application/json", strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("seeding %s: got %d want %d", doc, resp.StatusCode, http.StatusCreated)
+		}
+		ids = append(ids, strings.TrimPrefix(resp.Header.Get("Location"), "/api/resources/"))
+	}
+	return ids
+}
+
+// TestCLI Client commands read JSON or YAML and print JSON, YAML or tables. Each case runs against its own server
+// holding only the documents it seeds; {0}, {1}... in args stand for their ids.
+func TestCLI(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "doc.yaml")
+	if err := os.WriteFile(file, []byte("name: Arthur\nage: 40\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		seed  []string
+		stdin string
+		args  []string
+		// during runs while the command does, e.g. to change resources being watched.
+		during func(url string)
+		want   []string
+		err    bool
+		// stored and gone must and must not appear in the collection afterwards.
+		stored []string
+		gone   []string
+	}{
+		{name: "Create JSON - Success", stdin: `{"name":"Bruce","age":30}`, args: []string{"create"}, want: []string{`"name": "Bruce"`, `"id": "`}, stored: []string{"Bruce"}},
+		{name: "Create YAML - Success", stdin: "name: Clark\nage: 35\n", args: []string{"create", "-o", "yaml"}, want: []string{"name: Clark", "id: "}, stored: []string{"Clark"}},
+		{name: "Create File - Success", args: []string{"create", "--file", file}, want: []string{`"name": "Arthur"`}, stored: []string{"Arthur"}},
+		{name: "Create File - Missing Failure", args: []string{"create", "-f", file + ".missing"}, err: true},
+		{name: "Create - Failure", stdin: `{"name":`, args: []string{"create"}, err: true},
+		{name: "Import - Success", stdin: `[{"name":"Diana"}]`, args: []string{"import", "-o", "table"}, want: []string{"ID", "NAME", "Diana"}, stored: []string{"Diana"}},
+		{name: "Get - Success", seed: []string{`{"name":"Bruce"}`}, args: []string{"get", "{0}"}, want: []string{`"name": "Bruce"`}},
+		{name: "Get - Failure", args: []string{"get", "nope"}, err: true},
+		{name: "List Table - Success", seed: []string{`{"name":"Bruce","age":30}`, `{"name":"Clark","age":35}`}, args: []string{"list", "-o", "table"}, want: []string{"AGE", "NAME", "Bruce", "Clark"}},
+		{name: "List Filter - Success", seed: []string{`{"name":"Bruce","age":30}`, `{"name":"Clark","age":35}`}, args: []string{"list", "--filter", "age > 31", "-o", "yaml"}, want: []string{"name: Clark"}},
+		{name: "Update - Success", seed: []string{`{"name":"Bruce","age":30}`}, stdin: `{"name":"Bruce Wayne"}`, args: []string{"update", "{0}"}, want: []string{`"name": "Bruce Wayne"`}, stored: []string{"Bruce Wayne"}, gone: []string{`"age"`}},
+		{name: "Update File - Success", seed: []string{`{"name":"Bruce"}`}, args: []string{"update", "--file", file, "{0}"}, want: []string{`"name": "Arthur"`}, stored: []string{"Arthur"}, gone: []string{"Bruce"}},
+		{name: "Update - Missing ID Failure", stdin: `{"name":"Bruce"}`, args: []string{"update"}, err: true},
+		{name: "Patch - Success", seed: []string{`{"name":"Bruce","age":30}`}, stdin: "age: 31\n", args: []string{"patch", "-o", "yaml", "{0}"}, want: []string{"age: 31", "name: Bruce"}, stored: []string{`"age":31`}},
+		{name: "Patch - Not Exist Failure", stdin: `{"age":31}`, args: []string{"patch", "0bf8651a-0923-47b8-aed3-e9fc1505e497"}, err: true},
+		{name: "Delete - Success", seed: []string{`{"name":"Bruce"}`, `{"name":"Clark"}`, `{"name":"Diana"}`}, args: []string{"delete", "{0}", "{1}"}, stored: []string{"Diana"}, gone: []string{"Bruce", "Clark"}},
+		{name: "Delete - Missing ID Failure", args: []string{"delete"}, err: true},
+		{name: "Export - Success", seed: []string{`{"name":"Bruce"}`, `{"name":"Clark","age":35}`}, args: []string{"export", "--filter", "age > 31"}, want: []string{`"{1}": {`, "Clark"}},
+		{name: "Import Exported - Success", stdin: `{"a":{"name":"Bruce"},"b":{"name":"Clark"}}`, args: []string{"import"}, want: []string{"Bruce", "Clark"}, stored: []string{"Bruce", "Clark"}},
+		{name: "Watch - Success", args: []string{"watch", "--document", "-o", "yaml"}, during: func(url string) {
+			seed(t, url, `{"name":"Bruce"}`)
+		}, want: []string{"type: created", "name: Bruce"}},
+		{name: "Output - Failure", args: []string{"list", "-o", "xml"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(testRouter())
+			defer srv.Close()
+			ids := seed(t, srv.URL, tt.seed...)
+			expand := func(s string) string {
+				for n, id := range ids {
+					s = strings.ReplaceAll(s, "{"+string(rune('0'+n))+"}", id)
+				}
+				return s
+			}
+			args := make([]string, len(tt.args))
+			for n, a := range tt.args {
+				args[n] = expand(a)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.during != nil {
+				// Keep changing resources until the command, stopped by the deadline, has seen some.
+				ctx, cancel = context.WithTimeout(ctx, time.Second)
+				defer cancel()
+				go func() {
+					tick := time.NewTicker(50 * time.Millisecond)
+					defer tick.Stop()
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case <-tick.C:
+							tt.during(srv.URL)
+						}
+					}
+				}()
+			}
+
+			out, err := runCLI(ctx, t, srv.URL, tt.stdin, args...)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v want error %v", err, tt.err)
+			}
+			for _, w := range tt.want {
+				if w = expand(w); !strings.Contains(out, w) {
+					t.Errorf("got %q want it to contain %q", out, w)
+				}
+			}
+
+			resp, err := http.Get(srv.URL + "/api/resources")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			for _, s := range tt.stored {
+				if !strings.Contains(string(b), s) {
+					t.Errorf("got collection %s want it to contain %q", b, s)
+				}
+			}
+			for _, s := range tt.gone {
+				if strings.Contains(string(b), s) {
+					t.Errorf("got collection %s want it not to contain %q", b, s)
+				}
+			}
+		})
+	}
+}
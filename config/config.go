@@ -0,0 +1,276 @@
+// Package config loads the server configuration from defaults, a YAML or TOML file, GOREST_* environment
+// variables and command-line flags, each source overriding the previous one.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/ghodss/yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Duration A time.Duration written as a Go duration string ("90s", "1h30m") in config files.
+type Duration time.Duration
+
+// UnmarshalText Implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Duration Returns d as a time.Duration.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// MarshalText Implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config Server configuration.
+type Config struct {
+	Listen    string    `json:"listen" toml:"listen"`
+	TLS       TLS       `json:"tls" toml:"tls"`
+	Shutdown  Shutdown  `json:"shutdown" toml:"shutdown"`
+	Health    Health    `json:"health" toml:"health"`
+	Storage   Storage   `json:"storage" toml:"storage"`
+	Limits    Limits    `json:"limits" toml:"limits"`
+	Resources Resources `json:"resources" toml:"resources"`
+	Log       Log       `json:"log" toml:"log"`
+	Metrics   Metrics   `json:"metrics" toml:"metrics"`
+	Trace     Trace     `json:"trace" toml:"trace"`
+	RateLimit RateLimit `json:"rateLimit" toml:"rateLimit"`
+	Auth      Auth      `json:"auth" toml:"auth"`
+	Tenancy   Tenancy   `json:"tenancy" toml:"tenancy"`
+	Webhooks  Webhooks  `json:"webhooks" toml:"webhooks"`
+}
+
+// Shutdown Connection draining on SIGINT/SIGTERM.
+type Shutdown struct {
+	// Delay keeps serving after readiness turns false, so load balancers stop routing first.
+	Delay Duration `json:"delay" toml:"delay"`
+	// Timeout bounds how long in-flight requests may take to finish.
+	Timeout Duration `json:"timeout" toml:"timeout"`
+}
+
+// Health Checks run by /healthz and /readyz.
+type Health struct {
+	// Timeout bounds how long all checks of one probe may take.
+	Timeout Duration `json:"timeout" toml:"timeout"`
+	// Disk is a directory that must stay writable; empty skips the check.
+	Disk string `json:"disk" toml:"disk"`
+}
+
+// Storage Where resources are kept. Only the in-memory backend exists today.
+type Storage struct {
+	Backend string `json:"backend" toml:"backend"`
+}
+
+// Limits Request size limits.
+type Limits struct {
+	// MaxBodyBytes caps request bodies; zero is unlimited.
+	MaxBodyBytes int64 `json:"maxBodyBytes" toml:"maxBodyBytes"`
+}
+
+// Resources Behaviour of the resource store.
+type Resources struct {
+	SoftDelete     bool     `json:"softDelete" toml:"softDelete"`
+	TrashRetention Duration `json:"trashRetention" toml:"trashRetention"`
+	DefaultTTL     Duration `json:"defaultTTL" toml:"defaultTTL"`
+	HistorySize    int      `json:"historySize" toml:"historySize"`
+	Ownership      bool     `json:"ownership" toml:"ownership"`
+	AdminRole      string   `json:"adminRole" toml:"adminRole"`
+	// Schema is a JSON Schema file describing resource documents in /openapi.json.
+	Schema string `json:"schema" toml:"schema"`
+}
+
+// Log Access log settings.
+type Log struct {
+	Enabled bool     `json:"enabled" toml:"enabled"`
+	Format  string   `json:"format" toml:"format"`
+	Sample  float64  `json:"sample" toml:"sample"`
+	Redact  []string `json:"redact" toml:"redact"`
+}
+
+// Metrics Prometheus settings.
+type Metrics struct {
+	Enabled bool `json:"enabled" toml:"enabled"`
+}
+
+// Trace OpenTelemetry settings.
+type Trace struct {
+	Exporter string `json:"exporter" toml:"exporter"`
+	File     string `json:"file" toml:"file"`
+	Endpoint string `json:"endpoint" toml:"endpoint"`
+	// Insecure sends OTLP in plain HTTP instead of HTTPS.
+	Insecure bool `json:"insecure" toml:"insecure"`
+}
+
+// RateLimit Per-client rate limiting; a zero Rate leaves requests matching no rule unlimited.
+type RateLimit struct {
+	Rate  float64 `json:"rate" toml:"rate"`
+	Burst int     `json:"burst" toml:"burst"`
+	Key   string  `json:"key" toml:"key"`
+	// Store is memory to keep buckets in the limiter, or shared to keep them in the storage backend so every
+	// limiter using it draws from the same buckets.
+	Store string          `json:"store" toml:"store"`
+	Rules []RateLimitRule `json:"rules" toml:"rules"`
+}
+
+// RateLimitRule Overrides the default limit for a route template, e.g. /api/resources/{id}, and/or a method.
+// The first matching rule wins; a zero Rate exempts matching requests.
+type RateLimitRule struct {
+	Route  string  `json:"route" toml:"route"`
+	Method string  `json:"method" toml:"method"`
+	Rate   float64 `json:"rate" toml:"rate"`
+	Burst  int     `json:"burst" toml:"burst"`
+}
+
+// Auth Authentication and authorization. Authenticators are configured inline or read from File.
+type Auth struct {
+	mw.AuthConfig
+	File   string `json:"file" toml:"file"`
+	Policy string `json:"policy" toml:"policy"`
+}
+
+// Enabled Reports whether any authentication is configured.
+func (a *Auth) Enabled() bool {
+	return a.File != "" || a.ClientCert != nil || len(a.APIKeys) > 0 || len(a.Basic) > 0 || a.JWT != nil
+}
+
+// Tenancy Multi-tenant isolation.
+type Tenancy struct {
+	Enabled      bool   `json:"enabled" toml:"enabled"`
+	Header       string `json:"header" toml:"header"`
+	Domain       string `json:"domain" toml:"domain"`
+	MaxTenants   int    `json:"maxTenants" toml:"maxTenants"`
+	MaxResources int    `json:"maxResources" toml:"maxResources"`
+}
+
+// Webhooks Delivery of resource events to registered URLs.
+type Webhooks struct {
+	// AllowPrivate lets webhooks target loopback, link-local and private addresses.
+	AllowPrivate bool `json:"allowPrivate" toml:"allowPrivate"`
+}
+
+// Default Returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Listen:    ":8181",
+		TLS:       TLS{MinVersion: "1.2", ClientAuth: "none"},
+		Shutdown:  Shutdown{Timeout: Duration(30 * time.Second)},
+		Health:    Health{Timeout: Duration(2 * time.Second)},
+		Storage:   Storage{Backend: "memory"},
+		Resources: Resources{AdminRole: "admin", HistorySize: 32},
+		Log:       Log{Enabled: true, Format: "json"},
+		Metrics:   Metrics{Enabled: true},
+		Trace:     Trace{Exporter: "none", File: "traces.json", Endpoint: "localhost:4318", Insecure: true},
+		RateLimit: RateLimit{Burst: 20, Key: "ip", Store: "memory"},
+		Tenancy:   Tenancy{Header: mw.TenantHeader},
+	}
+}
+
+// ReadFile Overlays the YAML (.yaml, .yml, .json) or TOML (.toml) file at path onto c. Unknown keys are errors.
+func (c *Config) ReadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		var j []byte
+		j, err = yaml.YAMLToJSON(b)
+		if err != nil {
+			break
+		}
+		dec := json.NewDecoder(bytes.NewReader(j))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	default:
+		return fmt.Errorf("%s: unsupported config format, use .yaml, .yml, .json or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate Reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []string
+	check := func(ok bool, format string, v ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, v...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.Listen)
+	check(err == nil, "listen: %q is not a host:port address", c.Listen)
+	c.TLS.validate(check)
+	check(c.Shutdown.Delay >= 0, "shutdown.delay: must not be negative")
+	check(c.Shutdown.Timeout > 0, "shutdown.timeout: must be positive")
+	check(c.Health.Timeout > 0, "health.timeout: must be positive")
+	check(c.Storage.Backend == "memory", "storage.backend: unknown backend %q, only memory is supported", c.Storage.Backend)
+	check(c.Limits.MaxBodyBytes >= 0, "limits.maxBodyBytes: must not be negative")
+	check(c.Resources.TrashRetention >= 0, "resources.trashRetention: must not be negative")
+	check(c.Resources.DefaultTTL >= 0, "resources.defaultTTL: must not be negative")
+	check(c.Resources.HistorySize > 0, "resources.historySize: must be positive")
+	check(!c.Resources.Ownership || c.Resources.AdminRole != "", "resources.adminRole: required when ownership is enabled")
+	check(c.Log.Format == "json" || c.Log.Format == "text" || c.Log.Format == "logfmt", "log.format: must be json, text or logfmt, not %q", c.Log.Format)
+	check(c.Log.Sample >= 0 && c.Log.Sample <= 1, "log.sample: must be between 0 and 1")
+	switch c.Trace.Exporter {
+	case "none", "otlp":
+	case "file":
+		check(c.Trace.File != "", "trace.file: required by the file exporter")
+	default:
+		check(false, "trace.exporter: must be none, file or otlp, not %q", c.Trace.Exporter)
+	}
+	check(c.RateLimit.Rate >= 0, "rateLimit.rate: must not be negative")
+	check(c.RateLimit.Rate == 0 || c.RateLimit.Burst > 0, "rateLimit.burst: must be positive when rate limiting")
+	check(c.RateLimit.Key == "ip" || c.RateLimit.Key == "apikey" || strings.HasPrefix(c.RateLimit.Key, "header:") && len(c.RateLimit.Key) > len("header:"),
+		"rateLimit.key: must be ip, apikey or header:<name>, not %q", c.RateLimit.Key)
+	check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "shared", "rateLimit.store: must be memory or shared, not %q", c.RateLimit.Store)
+	for n, rule := range c.RateLimit.Rules {
+		check(rule.Route != "" || rule.Method != "", "rateLimit.rules[%d]: needs a route or a method", n)
+		check(rule.Rate >= 0, "rateLimit.rules[%d].rate: must not be negative", n)
+		check(rule.Rate == 0 || rule.Burst > 0, "rateLimit.rules[%d].burst: must be positive when rate limiting", n)
+	}
+	check(c.Auth.File == "" || len(c.Auth.APIKeys)+len(c.Auth.Basic) == 0 && c.Auth.JWT == nil && c.Auth.ClientCert == nil,
+		"auth.file: cannot be combined with inline authenticators")
+	check(c.Auth.ClientCert == nil || c.TLS.Mutual(), "auth.clientCert: requires tls.clientAuth request or require")
+	check(c.Tenancy.Header != "", "tenancy.header: must not be empty")
+	check(c.Tenancy.MaxTenants >= 0, "tenancy.maxTenants: must not be negative")
+	check(c.Tenancy.MaxResources >= 0, "tenancy.maxResources: must not be negative")
+	check(c.Tenancy.Domain == "" || !strings.Contains(c.Tenancy.Domain, ":"), "tenancy.domain: must not include a port")
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// AuthConfig Returns the authentication configuration, read from Auth.File when set.
+func (c *Config) AuthConfig() (*mw.AuthConfig, error) {
+	if c.Auth.File != "" {
+		return mw.LoadAuthConfig(c.Auth.File)
+	}
+	return &c.Auth.AuthConfig, nil
+}
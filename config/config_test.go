@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// load Runs Load as the action of an app started with args.
+func load(t *testing.T, args ...string) (*Config, error) {
+	var cfg *Config
+	var err error
+	app := &cli.App{Flags: Flags(), Action: func(c *cli.Context) error {
+		cfg, err = Load(c)
+		return nil
+	}}
+	if runErr := app.Run(append([]string{"gorest"}, args...)); runErr != nil {
+		t.Fatal(runErr)
+	}
+	return cfg, err
+}
+
+// write Creates a config file named name holding body.
+func write(t *testing.T, name, body string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoad Precedence of defaults, file, environment and flags.
+func TestLoad(t *testing.T) {
+	yml := write(t, "gorest.yaml", `
+listen: ":9000"
+resources:
+  defaultTTL: 90s
+rateLimit:
+  rate: 5
+  key: apikey
+  rules:
+    - route: /api/resources/{id}
+      method: DELETE
+      rate: 1
+      burst: 2
+auth:
+  apiKeys:
+    - hash: abc
+      subject: ci
+`)
+	tml := write(t, "gorest.toml", `
+listen = ":9001"
+[resources]
+trashRetention = "1h"
+softDelete = true
+`)
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(cfg *Config) bool
+	}{
+		{name: "Load - Defaults", check: func(cfg *Config) bool {
+			return cfg.Listen == ":8181" && cfg.Storage.Backend == "memory" && cfg.Log.Enabled && !cfg.Auth.Enabled()
+		}},
+		{name: "Load - YAML File", args: []string{"--config", yml}, check: func(cfg *Config) bool {
+			return cfg.Listen == ":9000" && cfg.Resources.DefaultTTL.Duration() == 90*time.Second &&
+				cfg.RateLimit.Rate == 5 && cfg.RateLimit.Burst == 20 && cfg.Auth.Enabled() && cfg.Auth.APIKeys[0].Subject == "ci" &&
+				len(cfg.RateLimit.Rules) == 1 && cfg.RateLimit.Rules[0].Method == "DELETE" && cfg.RateLimit.Rules[0].Burst == 2
+		}},
+		{name: "Load - TOML File", args: []string{"--config", tml}, check: func(cfg *Config) bool {
+			return cfg.Listen == ":9001" && cfg.Resources.SoftDelete && cfg.Resources.TrashRetention.Duration() == time.Hour
+		}},
+		{name: "Load - Env Over File", args: []string{"--config", yml}, env: map[string]string{"GOREST_LISTEN": ":9100"}, check: func(cfg *Config) bool {
+			return cfg.Listen == ":9100" && cfg.RateLimit.Rate == 5
+		}},
+		{name: "Load - Flag Over Env", args: []string{"--config", yml, "--listen", ":9200", "--rate-limit", "0"}, env: map[string]string{"GOREST_LISTEN": ":9100"}, check: func(cfg *Config) bool {
+			return cfg.Listen == ":9200" && cfg.RateLimit.Rate == 0
+		}},
+		{name: "Load - Logfmt Format", args: []string{"--log-format", "logfmt"}, check: func(cfg *Config) bool {
+			return cfg.Log.Format == "logfmt"
+		}},
+		{name: "Load - Config From Env", env: map[string]string{"GOREST_CONFIG": tml}, check: func(cfg *Config) bool {
+			return cfg.Listen == ":9001"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := load(t, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+		})
+	}
+}
+
+// TestLoad_Errors Invalid files and settings are reported at startup.
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "Load - Unknown Key Failure", args: []string{"--config", write(t, "bad.yaml", "lissen: \":1\"\n")}, want: []string{"lissen"}},
+		{name: "Load - Unknown TOML Key Failure", args: []string{"--config", write(t, "bad.toml", "lissen = \":1\"\n")}, want: []string{"bad.toml"}},
+		{name: "Load - Bad Duration Failure", args: []string{"--config", write(t, "ttl.yaml", "resources:\n  defaultTTL: soon\n")}, want: []string{"soon"}},
+		{name: "Load - Rate Limit Rule Failure", args: []string{"--config", write(t, "rules.yaml", "rateLimit:\n  rules:\n    - rate: 1\n    - route: /api/resources\n      rate: 1\n")},
+			want: []string{"rateLimit.rules[0]: needs a route or a method", "rateLimit.rules[1].burst"}},
+		{name: "Load - Unsupported Format Failure", args: []string{"--config", write(t, "gorest.ini", "")}, want: []string{"unsupported"}},
+		{name: "Load - Validation Failure", args: []string{"--listen", "8181", "--storage-backend", "redis", "--log-format", "xml", "--rate-key", "cookie", "--rate-store", "redis"},
+			want: []string{"listen", "storage.backend", "log.format", "rateLimit.key", "rateLimit.store"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.args...)
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q missing %q", err, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// binding A command-line flag, readable from GOREST_<NAME>, and how it overrides the config.
+type binding struct {
+	flag  cli.Flag
+	apply func(c *cli.Context, cfg *Config)
+}
+
+// env Returns the environment variable bound to flag name, e.g. GOREST_RATE_LIMIT for rate-limit.
+func env(name string) []string {
+	return []string{"GOREST_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))}
+}
+
+func stringFlag(name, usage string, value string, set func(cfg *Config, v string)) binding {
+	return binding{&cli.StringFlag{Name: name, Usage: usage, Value: value, EnvVars: env(name)},
+		func(c *cli.Context, cfg *Config) { set(cfg, c.String(name)) }}
+}
+
+func boolFlag(name, usage string, value bool, set func(cfg *Config, v bool)) binding {
+	return binding{&cli.BoolFlag{Name: name, Usage: usage, Value: value, EnvVars: env(name)},
+		func(c *cli.Context, cfg *Config) { set(cfg, c.Bool(name)) }}
+}
+
+func intFlag(name, usage string, value int64, set func(cfg *Config, v int64)) binding {
+	return binding{&cli.Int64Flag{Name: name, Usage: usage, Value: value, EnvVars: env(name)},
+		func(c *cli.Context, cfg *Config) { set(cfg, c.Int64(name)) }}
+}
+
+func floatFlag(name, usage string, value float64, set func(cfg *Config, v float64)) binding {
+	return binding{&cli.Float64Flag{Name: name, Usage: usage, Value: value, EnvVars: env(name)},
+		func(c *cli.Context, cfg *Config) { set(cfg, c.Float64(name)) }}
+}
+
+func durationFlag(name, usage string, value Duration, set func(cfg *Config, v Duration)) binding {
+	return binding{&cli.DurationFlag{Name: name, Usage: usage, Value: value.Duration(), EnvVars: env(name)},
+		func(c *cli.Context, cfg *Config) { set(cfg, Duration(c.Duration(name))) }}
+}
+
+// bindings Returns fresh flags, since urfave/cli flags keep parse state, with their config overrides.
+func bindings() []binding {
+	d := Default()
+	return []binding{
+		stringFlag("listen", "address to listen on", d.Listen, func(cfg *Config, v string) { cfg.Listen = v }),
+		stringFlag("tls-cert", "PEM certificate file; serves HTTPS together with --tls-key, reloaded on change", d.TLS.Cert, func(cfg *Config, v string) { cfg.TLS.Cert = v }),
+		stringFlag("tls-key", "PEM private key file of --tls-cert", d.TLS.Key, func(cfg *Config, v string) { cfg.TLS.Key = v }),
+		stringFlag("tls-min-version", "lowest TLS version accepted: 1.0, 1.1, 1.2 or 1.3", d.TLS.MinVersion, func(cfg *Config, v string) { cfg.TLS.MinVersion = v }),
+		stringFlag("tls-ciphers", "comma-separated TLS 1.0-1.2 cipher suites (empty uses Go's defaults)", "", func(cfg *Config, v string) {
+			cfg.TLS.Ciphers = nil
+			if v != "" {
+				cfg.TLS.Ciphers = strings.Split(v, ",")
+			}
+		}),
+		stringFlag("tls-client-ca", "PEM bundle client certificates are verified against", d.TLS.ClientCA, func(cfg *Config, v string) { cfg.TLS.ClientCA = v }),
+		stringFlag("tls-client-auth", "client certificates: none, request or require", d.TLS.ClientAuth, func(cfg *Config, v string) { cfg.TLS.ClientAuth = v }),
+		durationFlag("shutdown-delay", "keep serving this long after readiness turns false on shutdown", d.Shutdown.Delay, func(cfg *Config, v Duration) { cfg.Shutdown.Delay = v }),
+		durationFlag("shutdown-timeout", "how long in-flight requests may take to finish on shutdown", d.Shutdown.Timeout, func(cfg *Config, v Duration) { cfg.Shutdown.Timeout = v }),
+		durationFlag("health-timeout", "how long the checks of one /healthz or /readyz probe may take", d.Health.Timeout, func(cfg *Config, v Duration) { cfg.Health.Timeout = v }),
+		stringFlag("health-disk", "directory that must stay writable for the server to be healthy", d.Health.Disk, func(cfg *Config, v string) { cfg.Health.Disk = v }),
+		stringFlag("storage-backend", "resource storage backend", d.Storage.Backend, func(cfg *Config, v string) { cfg.Storage.Backend = v }),
+		intFlag("max-body-bytes", "largest request body accepted (0 is unlimited)", d.Limits.MaxBodyBytes, func(cfg *Config, v int64) { cfg.Limits.MaxBodyBytes = v }),
+		boolFlag("soft-delete", "move deleted resources to the trash instead of dropping them", d.Resources.SoftDelete, func(cfg *Config, v bool) { cfg.Resources.SoftDelete = v }),
+		durationFlag("trash-retention", "purge trashed resources after this long (0 keeps them until purged)", d.Resources.TrashRetention, func(cfg *Config, v Duration) { cfg.Resources.TrashRetention = v }),
+		durationFlag("default-ttl", "expire resources created without an explicit TTL after this long (0 disables)", d.Resources.DefaultTTL, func(cfg *Config, v Duration) { cfg.Resources.DefaultTTL = v }),
+		intFlag("history-size", "revisions kept per resource", int64(d.Resources.HistorySize), func(cfg *Config, v int64) { cfg.Resources.HistorySize = int(v) }),
+		boolFlag("ownership", "restrict each resource to its creating principal, its _groups and admins", d.Resources.Ownership, func(cfg *Config, v bool) { cfg.Resources.Ownership = v }),
+		stringFlag("admin-role", "role allowed to access every resource with ownership", d.Resources.AdminRole, func(cfg *Config, v string) { cfg.Resources.AdminRole = v }),
+		stringFlag("resource-schema", "JSON Schema file describing resource documents in /openapi.json", d.Resources.Schema, func(cfg *Config, v string) { cfg.Resources.Schema = v }),
+		boolFlag("access-log", "write the access log", d.Log.Enabled, func(cfg *Config, v bool) { cfg.Log.Enabled = v }),
+		stringFlag("log-format", "access log format: json, text or logfmt", d.Log.Format, func(cfg *Config, v string) { cfg.Log.Format = v }),
+		floatFlag("log-sample", "fraction of successful requests to log (0 logs all)", d.Log.Sample, func(cfg *Config, v float64) { cfg.Log.Sample = v }),
+		stringFlag("log-redact", "comma-separated access log fields or query parameters to redact", "", func(cfg *Config, v string) {
+			cfg.Log.Redact = nil
+			if v != "" {
+				cfg.Log.Redact = strings.Split(v, ",")
+			}
+		}),
+		boolFlag("metrics", "expose Prometheus metrics on /metrics", d.Metrics.Enabled, func(cfg *Config, v bool) { cfg.Metrics.Enabled = v }),
+		stringFlag("trace-exporter", "span exporter: none, file or otlp", d.Trace.Exporter, func(cfg *Config, v string) { cfg.Trace.Exporter = v }),
+		stringFlag("trace-file", "file receiving OTLP/JSON spans with the file exporter", d.Trace.File, func(cfg *Config, v string) { cfg.Trace.File = v }),
+		stringFlag("trace-endpoint", "OTLP/HTTP collector address", d.Trace.Endpoint, func(cfg *Config, v string) { cfg.Trace.Endpoint = v }),
+		boolFlag("trace-insecure", "send OTLP in plain HTTP instead of HTTPS", d.Trace.Insecure, func(cfg *Config, v bool) { cfg.Trace.Insecure = v }),
+		floatFlag("rate-limit", "requests per second allowed per client (0 disables)", d.RateLimit.Rate, func(cfg *Config, v float64) { cfg.RateLimit.Rate = v }),
+		intFlag("rate-burst", "burst size of each client's token bucket", int64(d.RateLimit.Burst), func(cfg *Config, v int64) { cfg.RateLimit.Burst = int(v) }),
+		stringFlag("rate-key", "rate limit client key: ip, apikey or header:<name>", d.RateLimit.Key, func(cfg *Config, v string) { cfg.RateLimit.Key = v }),
+		stringFlag("rate-store", "where rate limit buckets are kept: memory or shared (the storage backend)", d.RateLimit.Store, func(cfg *Config, v string) { cfg.RateLimit.Store = v }),
+		stringFlag("auth-config", "JSON file configuring API keys, JWT and Basic authentication", d.Auth.File, func(cfg *Config, v string) { cfg.Auth.File = v }),
+		stringFlag("authz-policy", "YAML file with the role-based access policy for /api routes", d.Auth.Policy, func(cfg *Config, v string) { cfg.Auth.Policy = v }),
+		boolFlag("tenancy", "give each tenant an isolated store, selected by header, subdomain or /t/{tenant}/api/", d.Tenancy.Enabled, func(cfg *Config, v bool) { cfg.Tenancy.Enabled = v }),
+		stringFlag("tenant-header", "header selecting the tenant", d.Tenancy.Header, func(cfg *Config, v string) { cfg.Tenancy.Header = v }),
+		stringFlag("tenant-domain", "base domain whose subdomains select the tenant", d.Tenancy.Domain, func(cfg *Config, v string) { cfg.Tenancy.Domain = v }),
+		intFlag("tenant-max-tenants", "most tenants that may be created (0 is unlimited)", int64(d.Tenancy.MaxTenants), func(cfg *Config, v int64) { cfg.Tenancy.MaxTenants = int(v) }),
+		intFlag("tenant-max-resources", "most resources each tenant may hold (0 is unlimited)", int64(d.Tenancy.MaxResources), func(cfg *Config, v int64) { cfg.Tenancy.MaxResources = int(v) }),
+		boolFlag("webhook-allow-private", "let webhooks target loopback, link-local and private addresses", d.Webhooks.AllowPrivate, func(cfg *Config, v bool) { cfg.Webhooks.AllowPrivate = v }),
+	}
+}
+
+// Flags Returns the command-line flags understood by Load, including -config.
+func Flags() []cli.Flag {
+	flags := []cli.Flag{&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "YAML or TOML configuration file", EnvVars: env("config")}}
+	for _, b := range bindings() {
+		flags = append(flags, b.flag)
+	}
+	return flags
+}
+
+// Load Builds the configuration for c: defaults, overlaid by the -config file, overlaid by every flag set on the
+// command line or through its environment variable, then validated.
+func Load(c *cli.Context) (*Config, error) {
+	cfg := Default()
+	if path := c.String("config"); path != "" {
+		if err := cfg.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range bindings() {
+		if c.IsSet(b.flag.Names()[0]) {
+			b.apply(c, cfg)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
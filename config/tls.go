@@ -0,0 +1,173 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLS HTTPS serving. TLS is enabled when Cert and Key are set; ClientAuth other than none enables mutual TLS.
+type TLS struct {
+	Cert       string   `json:"cert" toml:"cert"`
+	Key        string   `json:"key" toml:"key"`
+	MinVersion string   `json:"minVersion" toml:"minVersion"`
+	Ciphers    []string `json:"ciphers" toml:"ciphers"`
+	// ClientCA is the PEM bundle client certificates are verified against.
+	ClientCA string `json:"clientCA" toml:"clientCA"`
+	// ClientAuth is none, request (verify a certificate if one is sent) or require.
+	ClientAuth string `json:"clientAuth" toml:"clientAuth"`
+}
+
+// tlsVersions Accepted values of TLS.MinVersion.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Enabled Reports whether the server listens with TLS.
+func (t *TLS) Enabled() bool {
+	return t.Cert != "" || t.Key != ""
+}
+
+// Mutual Reports whether client certificates are verified.
+func (t *TLS) Mutual() bool {
+	return t.ClientAuth == "request" || t.ClientAuth == "require"
+}
+
+// validate Appends every invalid TLS setting to check.
+func (t *TLS) validate(check func(ok bool, format string, v ...interface{})) {
+	check(!t.Enabled() || t.Cert != "" && t.Key != "", "tls: cert and key must be set together")
+	_, ok := tlsVersions[t.MinVersion]
+	check(ok, "tls.minVersion: must be 1.0, 1.1, 1.2 or 1.3, not %q", t.MinVersion)
+	for _, name := range t.Ciphers {
+		_, err := cipherSuite(name)
+		check(err == nil, "tls.ciphers: %v", err)
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "request", "require":
+		check(t.Enabled(), "tls.clientAuth: requires tls.cert and tls.key")
+		check(t.ClientCA != "", "tls.clientCA: required when tls.clientAuth is %s", t.ClientAuth)
+	default:
+		check(false, "tls.clientAuth: must be none, request or require, not %q", t.ClientAuth)
+	}
+}
+
+// cipherSuite Returns the ID of the secure cipher suite called name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+func cipherSuite(name string) (uint16, error) {
+	for _, s := range tls.CipherSuites() {
+		if s.Name == name {
+			return s.ID, nil
+		}
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		if s.Name == name {
+			return 0, fmt.Errorf("cipher suite %s is insecure", name)
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+// certCheckInterval Least time between checks of the certificate files for changes.
+var certCheckInterval = time.Second
+
+// ServerConfig Builds the server's tls.Config. The certificate and key are re-read whenever either file changes,
+// so renewed certificates are picked up by new connections without a restart.
+func (t *TLS) ServerConfig() (*tls.Config, error) {
+	certs := &certReloader{cert: t.Cert, key: t.Key, interval: certCheckInterval}
+	if err := certs.reload(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     tlsVersions[t.MinVersion],
+	}
+	for _, name := range t.Ciphers {
+		id, err := cipherSuite(name)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+
+	if t.Mutual() {
+		pem, err := os.ReadFile(t.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates found", t.ClientCA)
+		}
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if t.ClientAuth == "require" {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// certReloader Serves a certificate pair, reloading it when the files' modification time changes.
+type certReloader struct {
+	cert, key string
+	// interval is the least time between checks of the files.
+	interval time.Duration
+
+	mu      sync.Mutex
+	current *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// GetCertificate Implements tls.Config.GetCertificate. A pair that fails to load is logged and the previous one
+// kept, so a half-written renewal does not take the server down.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) >= c.interval {
+		if err := c.load(); err != nil {
+			log.Printf("tls: keeping previous certificate: %v", err)
+		}
+	}
+	return c.current, nil
+}
+
+// reload Loads the pair if the files changed.
+func (c *certReloader) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+// load Loads the pair if the files changed since the last load. Caller must hold mu.
+func (c *certReloader) load() error {
+	c.checked = time.Now()
+	var latest time.Time
+	for _, path := range []string{c.cert, c.key} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	if c.current != nil && latest.Equal(c.modTime) {
+		return nil
+	}
+
+	pair, err := tls.LoadX509KeyPair(c.cert, c.key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.cert, err)
+	}
+	c.current, c.modTime = &pair, latest
+	return nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issue Creates a certificate for cn signed by parent (self-signed when nil) and returns it with its key.
+func issue(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, OrganizationalUnit: []string{"build"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid, tmpl.KeyUsage = true, true, x509.KeyUsageCertSign|x509.KeyUsageDigitalSignature
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+// writePEM Writes cert and key to PEM files in dir named after name.
+func writePEM(t *testing.T, dir, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	der, _ := x509.MarshalECPrivateKey(key)
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestTLS_Validate TLS settings are checked together.
+func TestTLS_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		tls  TLS
+		want string
+	}{
+		{name: "TLS - Disabled Success", tls: TLS{MinVersion: "1.2", ClientAuth: "none"}},
+		{name: "TLS - Mutual Success", tls: TLS{Cert: "c", Key: "k", MinVersion: "1.3", ClientCA: "ca", ClientAuth: "require",
+			Ciphers: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}},
+		{name: "TLS - Missing Key Failure", tls: TLS{Cert: "c", MinVersion: "1.2", ClientAuth: "none"}, want: "together"},
+		{name: "TLS - Bad Version Failure", tls: TLS{MinVersion: "1.4", ClientAuth: "none"}, want: "tls.minVersion"},
+		{name: "TLS - Insecure Cipher Failure", tls: TLS{MinVersion: "1.2", ClientAuth: "none", Ciphers: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, want: "insecure"},
+		{name: "TLS - Unknown Cipher Failure", tls: TLS{MinVersion: "1.2", ClientAuth: "none", Ciphers: []string{"ROT13"}}, want: "unknown"},
+		{name: "TLS - Missing Client CA Failure", tls: TLS{Cert: "c", Key: "k", MinVersion: "1.2", ClientAuth: "request"}, want: "tls.clientCA"},
+		{name: "TLS - Bad Client Auth Failure", tls: TLS{MinVersion: "1.2", ClientAuth: "always"}, want: "tls.clientAuth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.TLS = tt.tls
+			err := cfg.Validate()
+			if tt.want == "" && err != nil {
+				t.Errorf("got error %v want none", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("got error %v want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestTLS_ServerConfig Mutual TLS handshakes and certificate hot reload.
+func TestTLS_ServerConfig(t *testing.T) {
+	// Check the files on every handshake, so changes are seen by the next connection without waiting.
+	defer func(d time.Duration) { certCheckInterval = d }(certCheckInterval)
+	certCheckInterval = 0
+
+	dir := t.TempDir()
+	ca, caKey := issue(t, "gorest test CA", nil, nil)
+	caFile, _ := writePEM(t, dir, "ca", ca, caKey)
+	serverCert, serverKey := issue(t, "localhost", ca, caKey)
+	certFile, keyFile := writePEM(t, dir, "server", serverCert, serverKey)
+	clientCert, clientKey := issue(t, "ci", ca, caKey)
+
+	c := TLS{Cert: certFile, Key: keyFile, MinVersion: "1.2", ClientCA: caFile, ClientAuth: "require"}
+	cfg, err := c.ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.VerifiedChains[0][0].Subject.CommonName)
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(certs ...tls.Certificate) (string, *x509.Certificate, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, ServerName: "localhost"}}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return "", nil, err
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b), resp.TLS.PeerCertificates[0], nil
+	}
+	pair := tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}
+
+	body, peer, err := get(pair)
+	if err != nil || body != "ci" || !peer.Equal(serverCert) {
+		t.Fatalf("got %q, %v want ci", body, err)
+	}
+	if _, _, err := get(); err == nil {
+		t.Errorf("got nil error without client certificate")
+	}
+
+	// A renewed certificate is served by the next connection.
+	renewed, renewedKey := issue(t, "localhost", ca, caKey)
+	writePEM(t, dir, "server", renewed, renewedKey)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	if _, peer, err = get(pair); err != nil || !peer.Equal(renewed) {
+		t.Errorf("got %v, peer %v want renewed certificate", err, peer.SerialNumber)
+	}
+
+	// A broken renewal keeps the previous certificate.
+	os.WriteFile(certFile, []byte("garbage"), 0o600)
+	os.Chtimes(certFile, later.Add(time.Minute), later.Add(time.Minute))
+	if _, peer, err = get(pair); err != nil || !peer.Equal(renewed) {
+		t.Errorf("got %v want previous certificate after broken renewal", err)
+	}
+}
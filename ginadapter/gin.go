@@ -0,0 +1,58 @@
+// Package ginadapter serves the gorest resource API from a Gin router, using the same handlers as the server.
+package ginadapter
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+)
+
+// Register Registers the Endpoints of rh on group, e.g. engine.Group("/api"). Errors are rendered by the
+// handlers, in the same JSON format as on the server.
+func Register(group gin.IRoutes, rh *handlers.ResourceHandler) {
+	for _, rt := range rh.Endpoints() {
+		group.Handle(rt.Method, path(rt.Path), Handler(rt.Handler))
+	}
+}
+
+// Handler Adapts a resource handler to Gin, passing the route's parameters on as the mux.Vars it reads.
+func Handler(h http.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vars := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			vars[p.Key] = p.Value
+		}
+		h(c.Writer, mux.SetURLVars(c.Request, vars))
+	}
+}
+
+// Middleware Adapts net/http middleware, e.g. mw.Authentication, to Gin. The request it passes on, with any
+// context it added, continues down the Gin chain; a ResponseWriter it wraps does not, so middleware observing
+// responses, like mw.AccessLog, belongs around the Gin engine instead.
+func Middleware(m func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		called := false
+		m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// path Converts a gorilla/mux path template to Gin syntax: {id} becomes :id.
+func path(tpl string) string {
+	segs := strings.Split(tpl, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			segs[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,65 @@
+package ginadapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/angarcia/gorest/handlers/handlerstest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// TestConformance The Gin wiring serves the same resource API as the gorilla/mux one.
+func TestConformance(t *testing.T) {
+	handlerstest.Conformance(t, func(rh *handlers.ResourceHandler) http.Handler {
+		engine := gin.New()
+		Register(engine.Group("/api"), rh)
+		return engine
+	})
+}
+
+// TestMiddleware net/http middleware can end a request or pass it on with a new context.
+func TestMiddleware(t *testing.T) {
+	type key struct{}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-API-Key") != "secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, "alice")))
+		})
+	}
+	engine := gin.New()
+	engine.Use(Middleware(auth))
+	engine.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", c.Request.Context().Value(key{}))
+	})
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+		body string
+	}{
+		{name: "Middleware - Success", key: "secret", want: 200, body: "alice"},
+		{name: "Middleware - Failure", want: 401, body: "unauthorized\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/whoami", nil)
+			r.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+			if w.Code != tt.want || w.Body.String() != tt.body {
+				t.Errorf("got %d %q want %d %q", w.Code, w.Body, tt.want, tt.body)
+			}
+		})
+	}
+}
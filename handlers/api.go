@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Route A route of the resource API, with a gorilla/mux path template relative to the API prefix.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+}
+
+// Endpoints Returns the resource, transaction and trash routes of rh, in registration order: fixed paths precede the
+// {id} templates they would otherwise match. Handlers read path variables with mux.Vars.
+func (rh *ResourceHandler) Endpoints() []Route {
+	return []Route{
+		{http.MethodGet, "/resources/events", rh.GetEventsHandler},
+		{http.MethodGet, "/resources/ws", rh.GetWebSocketHandler},
+		{http.MethodGet, "/resources/{id}", rh.GetResourceHandler},
+		{http.MethodGet, "/resources", rh.GetResourcesHandler},
+		{http.MethodPost, "/resources", rh.CreateResourceHandler},
+		{http.MethodPut, "/resources/{id}", rh.UpdateResourceHandler},
+		{http.MethodPatch, "/resources/{id}", rh.PatchResourceHandler},
+		{http.MethodDelete, "/resources/{id}", rh.DeleteResourceHandler},
+		{http.MethodGet, "/resources/{id}/history", rh.GetHistoryHandler},
+		{http.MethodPost, "/resources/{id}/restore", rh.RestoreResourceHandler},
+		{http.MethodPost, "/transactions", rh.ExecuteTransactionHandler},
+		{http.MethodGet, "/trash", rh.GetTrashHandler},
+		{http.MethodDelete, "/trash/{id}", rh.PurgeTrashHandler},
+		{http.MethodPost, "/trash/{id}/restore", rh.RestoreTrashHandler},
+	}
+}
+
+// Routes Registers the Endpoints of rh on router, relative to its path prefix.
+func (rh *ResourceHandler) Routes(router *mux.Router) {
+	for _, rt := range rh.Endpoints() {
+		router.HandleFunc(rt.Path, rt.Handler).Methods(rt.Method)
+	}
+}
+
+// Option Configures the API created by CreateAPI.
+type Option func(*apiOptions)
+
+type apiOptions struct {
+	db          map[string]map[string]interface{}
+	marshal     func(v interface{}) ([]byte, error)
+	unmarshal   func(data []byte, v interface{}) error
+	contentType string
+	middleware  []mux.MiddlewareFunc
+	ids         IDPolicy
+	logger      *log.Logger
+}
+
+// WithStore Serves the documents of db, keyed by id, instead of an empty store. db must not be used elsewhere
+// while the API serves it.
+func WithStore(db map[string]map[string]interface{}) Option {
+	return func(o *apiOptions) {
+		o.db = db
+	}
+}
+
+// WithCodec Reads request bodies with unmarshal and writes documents with marshal, labelled contentType,
+// instead of JSON.
+func WithCodec(contentType string, marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) Option {
+	return func(o *apiOptions) {
+		o.contentType, o.marshal, o.unmarshal = contentType, marshal, unmarshal
+	}
+}
+
+// WithMiddleware Wraps every API route with mws, the first one outermost, e.g. mw.Authentication.
+// Requests matching no route do not pass through them.
+func WithMiddleware(mws ...mux.MiddlewareFunc) Option {
+	return func(o *apiOptions) {
+		o.middleware = append(o.middleware, mws...)
+	}
+}
+
+// WithIDPolicy Generates and validates resource ids with p instead of UUIDs.
+func WithIDPolicy(p IDPolicy) Option {
+	return func(o *apiOptions) {
+		o.ids = p
+	}
+}
+
+// WithLogger Writes handler log lines to l instead of the standard logger.
+func WithLogger(l *log.Logger) Option {
+	return func(o *apiOptions) {
+		o.logger = l
+	}
+}
+
+// API The resource API as an http.Handler, for embedding gorest into other services.
+type API struct {
+	router *mux.Router
+	rh     *ResourceHandler
+}
+
+// CreateAPI Returns the resource API with its routes under prefix, e.g. "/api", ready to be mounted on a router
+// serving that prefix:
+//
+//	router.PathPrefix("/api/").Handler(handlers.CreateAPI("/api"))
+//	serveMux.Handle("/api/", handlers.CreateAPI("/api"))
+func CreateAPI(prefix string, opts ...Option) *API {
+	o := apiOptions{ids: UUIDs}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.db == nil {
+		o.db = make(map[string]map[string]interface{})
+	}
+
+	rh := CreateHandler(o.db)
+	rh.ch = &CommonHandler{Marshaler: o.marshal, Unmarshaler: o.unmarshal, ContentType: o.contentType, Logger: o.logger}
+	rh.SetIDPolicy(o.ids)
+
+	router := mux.NewRouter().StrictSlash(true)
+	api := router
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix != "" {
+		api = router.PathPrefix(prefix + "/").Subrouter()
+	}
+	api.Use(o.middleware...)
+	rh.Routes(api)
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rh.ch.HttpError(w, "page not found", http.StatusNotFound)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rh.ch.HttpError(w, "method not allowed", http.StatusMethodNotAllowed)
+	})
+	return &API{router: router, rh: rh}
+}
+
+// ServeHTTP Implements http.Handler.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.router.ServeHTTP(w, r)
+}
+
+// Events Returns the broker receiving every change made through the API, e.g. for a WebhookDispatcher.
+func (a *API) Events() *EventBroker {
+	return a.rh.Events()
+}
+
+// Close Ends the API's event streams; register it with http.Server.RegisterOnShutdown so they do not hold up
+// a graceful shutdown.
+func (a *API) Close() {
+	a.rh.Close()
+}
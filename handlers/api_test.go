@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// sequence An IDPolicy numbering resources 1, 2, 3...
+type sequence struct {
+	n atomic.Int64
+}
+
+func (s *sequence) NewID() string {
+	return strconv.FormatInt(s.n.Add(1), 10)
+}
+
+func (s *sequence) Validate(id string) error {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return errors.New("id must be a number")
+	}
+	return nil
+}
+
+// TestCreateAPI The API serves its routes under the prefix when mounted on a gorilla/mux router or a ServeMux.
+func TestCreateAPI(t *testing.T) {
+	db := map[string]map[string]interface{}{"1": {"name": "Bruce"}}
+	var logs bytes.Buffer
+	api := CreateAPI("/v1/",
+		WithStore(db),
+		WithIDPolicy(&sequence{}),
+		WithLogger(log.New(&logs, "", 0)),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-API-Key") != "secret" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+
+	router := mux.NewRouter()
+	router.PathPrefix("/v1/").Handler(api)
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/v1/", api)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		key    string
+		want   int
+	}{
+		{name: "GetResource - Success", method: "GET", path: "/v1/resources/1", key: "secret", want: 200},
+		{name: "CreateResource - Success", method: "POST", path: "/v1/resources", body: `{"name":"Clark"}`, key: "secret", want: 201},
+		{name: "GetResource - Invalid ID Failure", method: "GET", path: "/v1/resources/abc", key: "secret", want: 400},
+		{name: "GetResources - Middleware Failure", method: "GET", path: "/v1/resources", want: 401},
+		{name: "GetResources - Prefix Failure", method: "GET", path: "/api/resources", key: "secret", want: 404},
+		{name: "GetWebhooks - Not Mounted Failure", method: "GET", path: "/v1/webhooks", key: "secret", want: 404},
+	}
+	for name, h := range map[string]http.Handler{"mux": router, "ServeMux": serveMux} {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				r, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				r.Header.Set("X-API-Key", tt.key)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != tt.want {
+					t.Errorf("got %d want %d: %s", w.Code, tt.want, w.Body)
+				}
+			})
+		}
+	}
+
+	if _, ok := db["2"]; !ok {
+		t.Errorf("got ids %v want 2 created by the id policy", db)
+	}
+	if !strings.Contains(logs.String(), "Resource Created: Id: 2") {
+		t.Errorf("got logs %q want the created resource logged", logs.String())
+	}
+}
+
+// TestCreateAPI_Codec Documents are read and written with the configured codec.
+func TestCreateAPI_Codec(t *testing.T) {
+	// A codec wrapping documents in an envelope, to tell it apart from plain JSON.
+	marshal := func(v interface{}) ([]byte, error) {
+		return json.Marshal(map[string]interface{}{"data": v})
+	}
+	unmarshal := func(data []byte, v interface{}) error {
+		var env struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &env); err != nil {
+			return err
+		}
+		if env.Data == nil {
+			return errors.New("missing data")
+		}
+		return json.Unmarshal(env.Data, v)
+	}
+	api := CreateAPI("", WithCodec("application/vnd.test+json", marshal, unmarshal))
+
+	r, _ := http.NewRequest("POST", "/resources", strings.NewReader(`{"data":{"name":"Bruce"}}`))
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	if w.Code != 201 {
+		t.Fatalf("got %d want %d: %s", w.Code, 201, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.test+json" {
+		t.Errorf("got Content-Type %q want %q", ct, "application/vnd.test+json")
+	}
+	if want := `{"data":{"name":"Bruce"}}`; w.Body.String() != want {
+		t.Errorf("got %s want %s", w.Body, want)
+	}
+
+	r, _ = http.NewRequest("POST", "/resources", strings.NewReader(`{"name":"Bruce"}`))
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	if w.Code != 400 {
+		t.Errorf("got %d want %d", w.Code, 400)
+	}
+}
@@ -2,18 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+
+	"github.com/angarcia/gorest/mw"
 )
 
 type ErrorHttp struct {
-	Status int    `json:"status"`
-	Msg    string `json:"message"`
+	Status    int    `json:"status"`
+	Msg       string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // CommonHandler Main struct for basic dependencies for API handlers
 type CommonHandler struct {
 	Marshaler   func(v interface{}) ([]byte, error)
 	Unmarshaler func(data []byte, v interface{}) error
+	// ContentType labels marshaled responses, application/json when empty.
+	ContentType string
+	// Logger receives handler log lines, the standard logger when nil.
+	Logger *log.Logger
 }
 
 // Marshal Will marshal provided data with Marshaler defined in ch.
@@ -35,9 +43,35 @@ func (ch *CommonHandler) Unmarshal(data []byte, v interface{}) error {
 }
 
 // HttpError Custom error function to report HTTP request errors in application/json format instead of test/plain
+// The request id echoed on w by mw.RequestID, if any, is included in the body.
 func (ch *CommonHandler) HttpError(w http.ResponseWriter, err string, code int) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(ErrorHttp{Status: code, Msg: err})
+	json.NewEncoder(w).Encode(ErrorHttp{Status: code, Msg: err, RequestID: w.Header().Get(mw.RequestIDHeader)})
+}
+
+// contentType Returns the media type of marshaled responses.
+func (ch *CommonHandler) contentType() string {
+	if ch.ContentType == "" {
+		return "application/json"
+	}
+	return ch.ContentType
+}
+
+// logf Handler log line prefixed with the id mw.RequestID stored on r, so it can be tied to the access log.
+func (ch *CommonHandler) logf(r *http.Request, format string, v ...interface{}) {
+	if id := mw.RequestIDFrom(r.Context()); id != "" {
+		format = "[" + id + "] " + format
+	}
+	ch.printf(format, v...)
+}
+
+// printf Handler log line not tied to a request, e.g. from the sweeper.
+func (ch *CommonHandler) printf(format string, v ...interface{}) {
+	if ch.Logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	ch.Logger.Printf(format, v...)
 }
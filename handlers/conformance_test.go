@@ -0,0 +1,19 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/angarcia/gorest/handlers/handlerstest"
+	"github.com/gorilla/mux"
+)
+
+// TestConformance The gorilla/mux wiring used by the server serves the resource API.
+func TestConformance(t *testing.T) {
+	handlerstest.Conformance(t, func(rh *handlers.ResourceHandler) http.Handler {
+		router := mux.NewRouter().StrictSlash(true)
+		rh.Routes(router.PathPrefix("/api/").Subrouter())
+		return router
+	})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types published on every successful write.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// Event A single change applied to the resource store.
+type Event struct {
+	Seq      uint64                 `json:"-"`
+	Type     string                 `json:"type"`
+	ID       string                 `json:"id"`
+	Version  int                    `json:"version"`
+	Time     time.Time              `json:"time"`
+	Document map[string]interface{} `json:"document,omitempty"`
+	Tenant   string                 `json:"tenant,omitempty"`
+}
+
+// Subscription Receiving end of the broker. C is closed when the subscriber falls too far behind
+// or is cancelled, after which the consumer should resume from its last seen sequence.
+type Subscription struct {
+	C  chan Event
+	eb *EventBroker
+}
+
+// Close Removes the subscription from the broker.
+func (s *Subscription) Close() {
+	s.eb.unsubscribe(s)
+}
+
+// EventBroker Fan-out of store changes with a bounded replay buffer.
+type EventBroker struct {
+	mu      sync.Mutex
+	seq     uint64
+	buf     []Event
+	size    int
+	backlog int
+	subs    map[*Subscription]struct{}
+	hooks   []func(Event)
+	closed  bool
+}
+
+// CreateEventBroker creation/initialization of event broker keeping the last size events for replay.
+// Each subscriber may lag at most backlog events before it is dropped.
+func CreateEventBroker(size, backlog int) *EventBroker {
+	return &EventBroker{
+		size:    size,
+		backlog: backlog,
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish Assigns the next sequence number to e and delivers it to every subscriber without blocking.
+func (eb *EventBroker) Publish(e Event) {
+	if eb == nil {
+		return
+	}
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.seq++
+	e.Seq = eb.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	eb.buf = append(eb.buf, e)
+	if len(eb.buf) > eb.size {
+		eb.buf = eb.buf[len(eb.buf)-eb.size:]
+	}
+
+	for _, h := range eb.hooks {
+		h(e)
+	}
+	for s := range eb.subs {
+		select {
+		case s.C <- e:
+		default:
+			// Slow consumer: drop it rather than stall the write path.
+			delete(eb.subs, s)
+			close(s.C)
+		}
+	}
+}
+
+// Subscribe Registers a new subscriber and returns the buffered events after sequence last.
+// gap reports whether events after last have already been evicted from the buffer.
+func (eb *EventBroker) Subscribe(last uint64) (sub *Subscription, replay []Event, gap bool) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if len(eb.buf) > 0 && last+1 < eb.buf[0].Seq {
+		gap = true
+	}
+	for _, e := range eb.buf {
+		if e.Seq > last {
+			replay = append(replay, e)
+		}
+	}
+
+	sub = &Subscription{C: make(chan Event, eb.backlog), eb: eb}
+	if eb.closed {
+		close(sub.C)
+		return sub, replay, gap
+	}
+	eb.subs[sub] = struct{}{}
+	return sub, replay, gap
+}
+
+// Close Ends every subscription so streaming consumers return. Later subscriptions start closed.
+func (eb *EventBroker) Close() {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.closed = true
+	for s := range eb.subs {
+		delete(eb.subs, s)
+		close(s.C)
+	}
+}
+
+// Closed Reports whether Close has been called.
+func (eb *EventBroker) Closed() bool {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	return eb.closed
+}
+
+// Attach Registers fn to be called with every published event, in order, while the broker lock is held.
+// fn must not block or call back into the broker.
+func (eb *EventBroker) Attach(fn func(Event)) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.hooks = append(eb.hooks, fn)
+}
+
+// Last Returns the sequence number of the most recently published event.
+func (eb *EventBroker) Last() uint64 {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	return eb.seq
+}
+
+func (eb *EventBroker) unsubscribe(s *Subscription) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if _, ok := eb.subs[s]; ok {
+		delete(eb.subs, s)
+		close(s.C)
+	}
+}
+
+// publish Emits a change for resource i. Caller must hold rh.dh.mu so events follow write order.
+func (rh *ResourceHandler) publish(t string, i string, v int, doc map[string]interface{}) {
+	rh.eb.Publish(Event{Type: t, ID: i, Version: v, Document: doc, Tenant: rh.tenant})
+}
+
+// visible Reports whether e concerns a resource of rh's tenant that the principal of r may access.
+func (rh *ResourceHandler) visible(r *http.Request, e Event) bool {
+	return e.Tenant == rh.tenant && rh.canAccess(r, e.Document)
+}
+
+// writeEvent Writes e in text/event-stream framing, leaving out the document unless requested.
+func writeEvent(w http.ResponseWriter, e Event, doc bool) error {
+	if !doc {
+		e.Document = nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+	return err
+}
+
+// GetEventsHandler GET /api/resources/events
+func (rh *ResourceHandler) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	f, ok := w.(http.Flusher)
+	if !ok || rh.eb == nil {
+		rh.ch.HttpError(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Without Last-Event-ID only events published from now on are delivered, as on the websocket.
+	last, fresh := rh.eb.Last(), true
+	if h := r.Header.Get("Last-Event-ID"); h != "" {
+		n, err := strconv.ParseUint(h, 10, 64)
+		if err != nil {
+			rh.ch.logf(r, "error: %v", err)
+			rh.ch.HttpError(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		last, fresh = n, false
+	}
+	doc := r.URL.Query().Get("document") == "true"
+
+	sub, replay, gap := rh.eb.Subscribe(last)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// Give fresh subscribers their starting position, an id without data, so reconnects resume from it.
+	if fresh {
+		fmt.Fprintf(w, "id: %d\n\n", last)
+	}
+	// Tell the client it missed events and should re-read the collection.
+	if gap {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		if !rh.visible(r, e) {
+			continue
+		}
+		if err := writeEvent(w, e, doc); err != nil {
+			rh.ch.logf(r, "error: %v", err)
+			return
+		}
+	}
+	f.Flush()
+	rh.ch.logf(r, "Event Stream Opened: Replayed: %v\n", len(replay))
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			rh.ch.logf(r, "Event Stream Closed\n")
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			f.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				if rh.eb.Closed() {
+					rh.ch.logf(r, "Event Stream Closed: server shutting down\n")
+					return
+				}
+				rh.ch.logf(r, "Event Stream Dropped: consumer too slow\n")
+				return
+			}
+			if !rh.visible(r, e) {
+				continue
+			}
+			if err := writeEvent(w, e, doc); err != nil {
+				rh.ch.logf(r, "error: %v", err)
+				return
+			}
+			f.Flush()
+		}
+	}
+}
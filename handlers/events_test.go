@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEventBroker_Subscribe Replay and gap detection from the bounded buffer.
+func TestEventBroker_Subscribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		events int
+		last   uint64
+		replay int
+		gap    bool
+	}{
+		{name: "Subscribe - Empty Buffer", events: 0, last: 0, replay: 0, gap: false},
+		{name: "Subscribe - Replay All", events: 3, last: 0, replay: 3, gap: false},
+		{name: "Subscribe - Resume", events: 3, last: 2, replay: 1, gap: false},
+		{name: "Subscribe - Evicted Gap", events: 6, last: 1, replay: 4, gap: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eb := CreateEventBroker(4, 1)
+			for n := 0; n < tt.events; n++ {
+				eb.Publish(Event{Type: EventCreated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497", Version: 1})
+			}
+
+			sub, replay, gap := eb.Subscribe(tt.last)
+			defer sub.Close()
+			if len(replay) != tt.replay {
+				t.Errorf("got %d want %d", len(replay), tt.replay)
+			}
+			if gap != tt.gap {
+				t.Errorf("got %v want %v", gap, tt.gap)
+			}
+		})
+	}
+}
+
+// TestEventBroker_Publish A subscriber that stops reading is dropped instead of blocking writers.
+func TestEventBroker_Publish(t *testing.T) {
+	eb := CreateEventBroker(8, 1)
+	sub, _, _ := eb.Subscribe(0)
+
+	eb.Publish(Event{Type: EventCreated})
+	eb.Publish(Event{Type: EventUpdated})
+
+	if e, ok := <-sub.C; !ok || e.Seq != 1 {
+		t.Errorf("got %v want first event", e)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Errorf("slow subscriber was not dropped")
+	}
+	sub.Close()
+}
+
+// TestEventBroker_Close Open and later subscriptions end so streaming handlers return on shutdown.
+func TestEventBroker_Close(t *testing.T) {
+	eb := CreateEventBroker(4, 1)
+	sub, _, _ := eb.Subscribe(0)
+	eb.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Errorf("got open subscription want closed")
+	}
+	later, _, _ := eb.Subscribe(0)
+	if _, ok := <-later.C; ok {
+		t.Errorf("got open late subscription want closed")
+	}
+	sub.Close()
+	later.Close()
+
+	rh := &ResourceHandler{ch: &CommonHandler{}, dh: &DBHelper{db: map[string]map[string]interface{}{}}, eb: CreateEventBroker(4, 1)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r, _ := http.NewRequest("GET", "/", nil)
+		rh.GetEventsHandler(httptest.NewRecorder(), r)
+	}()
+	// Close once the stream is open, so it is the open subscription that ends.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rh.eb.mu.Lock()
+		open := len(rh.eb.subs)
+		rh.eb.mu.Unlock()
+		if open == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("event stream did not open")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	rh.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("event stream still open after Close")
+	}
+}
+
+// TestResourceHandler_GetEventsHandler GET /api/resources/events
+func TestResourceHandler_GetEventsHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		lastID   string
+		query    string
+		wrap     bool
+		want     int
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "GetEvents - Success",
+			lastID:   "0",
+			want:     200,
+			contains: []string{"id: 1\nevent: created", "id: 2\nevent: updated"},
+			excludes: []string{"Bruce"},
+		},
+		{
+			name:     "GetEvents - Resume With Document",
+			lastID:   "1",
+			query:    "?document=true",
+			want:     200,
+			contains: []string{"id: 2\nevent: updated", "Bruce"},
+			excludes: []string{"id: 1\n"},
+		},
+		{
+			name:     "GetEvents - Fresh Subscriber No Replay",
+			want:     200,
+			contains: []string{"id: 2\n\n"},
+			excludes: []string{"event: "},
+		},
+		{
+			name:     "GetEvents - Fresh Subscriber After Wraparound No Replay And No Reset",
+			wrap:     true,
+			want:     200,
+			contains: []string{"id: 20\n\n"},
+			excludes: []string{"event: "},
+		},
+		{
+			name:     "GetEvents - Resume After Wraparound Reset",
+			lastID:   "1",
+			wrap:     true,
+			want:     200,
+			contains: []string{"event: reset", "id: 20\n"},
+			excludes: []string{"id: 2\n"},
+		},
+		{
+			name:   "GetEvents - Invalid Last-Event-ID Failure",
+			lastID: "dummy",
+			want:   400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Setup Request
+			rh := ResourceHandler{
+				ch: &CommonHandler{},
+				dh: &DBHelper{
+					db: map[string]map[string]interface{}{},
+					mu: sync.Mutex{},
+				},
+				eb: CreateEventBroker(16, 16),
+			}
+			rh.publish(EventCreated, "0bf8651a-0923-47b8-aed3-e9fc1505e497", 1, map[string]interface{}{"name": "Clark"})
+			rh.publish(EventUpdated, "0bf8651a-0923-47b8-aed3-e9fc1505e497", 2, map[string]interface{}{"name": "Bruce"})
+			// Overflow the 16 event buffer so the first events are no longer replayable.
+			for v := 3; tt.wrap && v <= 20; v++ {
+				rh.publish(EventUpdated, "0bf8651a-0923-47b8-aed3-e9fc1505e497", v, map[string]interface{}{"name": "Bruce"})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			r, err := http.NewRequestWithContext(ctx, "GET", "/api/resources/events"+tt.query, nil)
+			if err != nil {
+				t.Fail()
+			}
+			if tt.lastID != "" {
+				r.Header.Set("Last-Event-ID", tt.lastID)
+			}
+
+			w := httptest.NewRecorder()
+			rh.GetEventsHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Errorf("body %q missing %q", w.Body.String(), s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(w.Body.String(), s) {
+					t.Errorf("body %q should not contain %q", w.Body.String(), s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TTL may be supplied per write through the header or the reserved body field, the header taking precedence.
+// Either form accepts whole seconds or a Go duration string such as "90s"; zero clears the expiry.
+const (
+	TTLHeader     = "X-Resource-TTL"
+	ExpiresHeader = "X-Resource-Expires"
+	ttlField      = "_ttl"
+)
+
+// SetDefaultTTL Applies d to resources created without an explicit TTL. Zero disables the default.
+func (rh *ResourceHandler) SetDefaultTTL(d time.Duration) {
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	rh.dh.ttl = d
+}
+
+// maxTTLSeconds Largest TTL in seconds that fits a time.Duration.
+const maxTTLSeconds = math.MaxInt64 / int64(time.Second)
+
+// errTTLRange TTL too large for a time.Duration.
+var errTTLRange = fmt.Errorf("ttl must be at most %d seconds", maxTTLSeconds)
+
+// parseTTL Parses whole seconds or a duration string. TTLs a time.Duration cannot hold are rejected rather than
+// overflowing into negative ones.
+func parseTTL(v interface{}) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	switch t := v.(type) {
+	case float64:
+		if t > float64(maxTTLSeconds) {
+			return 0, errTTLRange
+		}
+		d = time.Duration(t * float64(time.Second))
+	case string:
+		if n, aerr := strconv.ParseInt(t, 10, 64); aerr == nil {
+			if n > maxTTLSeconds {
+				return 0, errTTLRange
+			}
+			d = time.Duration(n) * time.Second
+		} else if errors.Is(aerr, strconv.ErrRange) {
+			return 0, errTTLRange
+		} else {
+			d, err = time.ParseDuration(t)
+		}
+	default:
+		err = errors.New("ttl must be seconds or a duration string")
+	}
+	if err == nil && d < 0 {
+		err = errors.New("ttl must not be negative")
+	}
+	return d, err
+}
+
+// ttlFrom Extracts the TTL requested for a write and strips the reserved field from obj.
+// set is false when the request carries no TTL.
+func ttlFrom(r *http.Request, obj map[string]interface{}) (d time.Duration, set bool, err error) {
+	d, set, err = bodyTTL(obj)
+	if h := r.Header.Get(TTLHeader); h != "" {
+		d, err = parseTTL(h)
+		return d, true, err
+	}
+	return d, set, err
+}
+
+// bodyTTL Extracts the TTL of the reserved body field and strips it from obj.
+func bodyTTL(obj map[string]interface{}) (d time.Duration, set bool, err error) {
+	field, ok := obj[ttlField]
+	if !ok {
+		return 0, false, nil
+	}
+	delete(obj, ttlField)
+	d, err = parseTTL(field)
+	return d, true, err
+}
+
+// applyTTL Sets or clears the expiry of resource i and reports it on the response. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) applyTTL(w http.ResponseWriter, i string, d time.Duration, set bool) {
+	if set {
+		rh.dh.setTTL(i, d)
+	}
+	if at, ok := rh.dh.exp[i]; ok {
+		w.Header().Set(ExpiresHeader, at.Format(time.RFC3339Nano))
+	}
+}
+
+// setTTL Expires resource i after d, or never when d is zero. Caller must hold mu.
+func (dh *DBHelper) setTTL(i string, d time.Duration) {
+	if dh.exp == nil {
+		dh.exp = make(map[string]time.Time)
+	}
+	if d > 0 {
+		dh.exp[i] = time.Now().UTC().Add(d)
+	} else {
+		delete(dh.exp, i)
+	}
+}
+
+// expired Reports whether live resource i is past its expiry at now. Requests treat such resources as absent until
+// the sweeper reaps them. Caller must hold mu.
+func (dh *DBHelper) expired(i string, now time.Time) bool {
+	at, ok := dh.exp[i]
+	if _, live := dh.db[i]; !live {
+		return false
+	}
+	return ok && !at.After(now)
+}
+
+// expire Deletes every resource whose expiry is not after now, together with its history, so expired documents
+// cannot be read back through revisions. Run by the sweeper; caller must hold rh.dh.mu.
+func (rh *ResourceHandler) expire(now time.Time) {
+	for i := range rh.dh.exp {
+		if !rh.dh.expired(i, now) {
+			continue
+		}
+		delete(rh.dh.exp, i)
+		old, ok := rh.dh.db[i]
+		if !ok {
+			continue
+		}
+		v := rh.dh.version(i) + 1
+		delete(rh.dh.db, i)
+		delete(rh.dh.hist, i)
+		rh.publish(EventDeleted, i, v, old)
+		rh.ch.printf("Resource Expired: %v\n", i)
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TestResourceHandler_CreateResourceHandler_TTL POST /api/resources/ with TTL header, field and default
+func TestResourceHandler_CreateResourceHandler_TTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		header  string
+		def     time.Duration
+		want    int
+		expires bool
+	}{
+		{name: "CreateResource - No TTL", body: `{"name":"Bruce"}`, want: 201},
+		{name: "CreateResource - Header TTL", body: `{"name":"Bruce"}`, header: "60", want: 201, expires: true},
+		{name: "CreateResource - Field TTL", body: `{"name":"Bruce","_ttl":"1m"}`, want: 201, expires: true},
+		{name: "CreateResource - Default TTL", body: `{"name":"Bruce"}`, def: time.Minute, want: 201, expires: true},
+		{name: "CreateResource - Zero TTL Overrides Default", body: `{"name":"Bruce","_ttl":0}`, def: time.Minute, want: 201},
+		{name: "CreateResource - Invalid TTL Failure", body: `{"name":"Bruce"}`, header: "soon", want: 400},
+		{name: "CreateResource - Negative TTL Failure", body: `{"name":"Bruce","_ttl":-5}`, want: 400},
+		{name: "CreateResource - Overflowing TTL Failure", body: `{"name":"Bruce","_ttl":1e30}`, want: 400},
+		{name: "CreateResource - Overflowing Header TTL Failure", body: `{"name":"Bruce"}`, header: "9223372037", want: 400},
+		{name: "CreateResource - Out Of Range Header TTL Failure", body: `{"name":"Bruce"}`, header: "99999999999999999999", want: 400},
+		{name: "CreateResource - Overflowing Duration TTL Failure", body: `{"name":"Bruce","_ttl":"3000000h"}`, want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := ResourceHandler{
+				ch: &CommonHandler{},
+				dh: &DBHelper{
+					db:  map[string]map[string]interface{}{},
+					ttl: tt.def,
+					mu:  sync.Mutex{},
+				},
+			}
+			r, _ := http.NewRequest("POST", "", strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set(TTLHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			rh.CreateResourceHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get(ExpiresHeader) != ""; got != tt.expires {
+				t.Errorf("expires header: got %v want %v", got, tt.expires)
+			}
+			if strings.Contains(w.Body.String(), ttlField) {
+				t.Errorf("reserved field stored: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestResourceHandler_expire Expired resources are hidden from reads, including their history, and reaped with it
+// by the sweeper.
+func TestResourceHandler_expire(t *testing.T) {
+	gone, kept := "0bf8651a-0923-47b8-aed3-e9fc1505e497", "0bf8651a-0923-47b8-aed3-e9fc1505e496"
+	rh := ResourceHandler{
+		ch: &CommonHandler{},
+		dh: &DBHelper{
+			db: map[string]map[string]interface{}{
+				gone: {"name": "Clark"},
+				kept: {"name": "Bruce"},
+			},
+			exp: map[string]time.Time{
+				gone: time.Now().Add(-time.Second),
+				kept: time.Now().Add(time.Hour),
+			},
+			mu: sync.Mutex{},
+		},
+		eb: CreateEventBroker(4, 4),
+	}
+	rh.dh.record(gone, rh.dh.db[gone], false)
+	rh.dh.record(kept, rh.dh.db[kept], false)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		query   string
+		want    int
+		body    string
+	}{
+		{name: "GetResource - Expired Failure", handler: rh.GetResourceHandler, id: gone, want: 400},
+		{name: "GetResource - Expired Version Failure", handler: rh.GetResourceHandler, id: gone, query: "?version=1", want: 404},
+		{name: "GetHistory - Expired Failure", handler: rh.GetHistoryHandler, id: gone, want: 404},
+		{name: "PatchResource - Expired Failure", handler: rh.PatchResourceHandler, id: gone, want: 400},
+		{name: "GetResources - Expired Hidden Success", handler: rh.GetResourcesHandler, want: 200, body: "Bruce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/"+tt.query, strings.NewReader(`{}`))
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			tt.handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) || strings.Contains(w.Body.String(), "Clark") {
+				t.Errorf("body %q missing %q or showing the expired resource", w.Body.String(), tt.body)
+			}
+		})
+	}
+	if rh.eb.Last() != 0 {
+		t.Errorf("got %d events want %d: reads must leave reaping to the sweeper", rh.eb.Last(), 0)
+	}
+
+	rh.sweep(time.Now())
+	if _, ok := rh.dh.db[gone]; ok || len(rh.dh.hist[gone]) != 0 {
+		t.Errorf("got db %v history %v want the expired resource and its history dropped", rh.dh.db, rh.dh.hist)
+	}
+	if rh.eb.Last() != 1 {
+		t.Errorf("got %d events want %d", rh.eb.Last(), 1)
+	}
+
+	rh.sweep(time.Now().Add(2 * time.Hour))
+	if len(rh.dh.db) != 0 || len(rh.dh.exp) != 0 || len(rh.dh.hist) != 0 {
+		t.Errorf("got db %v exp %v hist %v want all empty", rh.dh.db, rh.dh.exp, rh.dh.hist)
+	}
+}
+
+// TestResourceHandler_Restore_TTL Restores recreating a resource give it the default TTL and drop any stale expiry;
+// restoring over a live resource keeps its expiry.
+func TestResourceHandler_Restore_TTL(t *testing.T) {
+	i := "0bf8651a-0923-47b8-aed3-e9fc1505e497"
+	live := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		handler func(rh *ResourceHandler) http.HandlerFunc
+		trashed bool
+		stale   bool
+		def     time.Duration
+		want    int
+		expires bool
+	}{
+		{name: "RestoreResource - Recreate Default TTL Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreResourceHandler }, trashed: true, def: time.Minute, want: 201, expires: true},
+		{name: "RestoreResource - Recreate Stale Expiry Cleared", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreResourceHandler }, trashed: true, stale: true, want: 201},
+		{name: "RestoreResource - Live Keeps Expiry", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreResourceHandler }, def: time.Minute, want: 202, expires: true},
+		{name: "RestoreTrash - Default TTL Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreTrashHandler }, trashed: true, def: time.Minute, want: 201, expires: true},
+		{name: "RestoreTrash - Stale Expiry Cleared", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreTrashHandler }, trashed: true, stale: true, want: 201},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := CreateHandler(map[string]map[string]interface{}{i: {"name": "Bruce"}})
+			rh.EnableSoftDelete(0)
+			rh.dh.record(i, rh.dh.db[i], false)
+			rh.dh.exp = map[string]time.Time{i: live}
+			if tt.trashed {
+				rh.dh.remove(i)
+			}
+			if tt.stale {
+				rh.dh.exp[i] = time.Now().Add(-time.Hour)
+			}
+			rh.SetDefaultTTL(tt.def)
+
+			r, _ := http.NewRequest("POST", "/?version=1", strings.NewReader(""))
+			r = mux.SetURLVars(r, map[string]string{"id": i})
+			w := httptest.NewRecorder()
+			tt.handler(rh)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("got %d want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			at, ok := rh.dh.exp[i]
+			if got := w.Header().Get(ExpiresHeader) != ""; got != tt.expires || ok != tt.expires {
+				t.Errorf("expires header: got %v expiry %v want %v", got, at, tt.expires)
+			}
+			switch {
+			case tt.expires && !tt.trashed && !at.Equal(live):
+				t.Errorf("got expiry %v want the live %v kept", at, live)
+			case tt.expires && tt.trashed && (at.Before(time.Now()) || at.After(time.Now().Add(tt.def))):
+				t.Errorf("got expiry %v want within %v", at, tt.def)
+			}
+			if rh.dh.expired(i, time.Now()) {
+				t.Errorf("got the restored resource expired")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter Conjunction of field comparisons parsed from an expression such as `name == "Bruce" and age >= 30`.
+// Nested fields are addressed with dots, e.g. `address.city == "Gotham"`.
+type Filter struct {
+	conds []cond
+}
+
+type cond struct {
+	path []string
+	op   string
+	val  interface{}
+}
+
+var filterOps = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// ParseFilter Parses expr into a Filter. An empty expression matches every document.
+func ParseFilter(expr string) (*Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{}
+	for n := 0; n < len(toks); {
+		if len(toks)-n < 3 {
+			return nil, errors.New("filter: incomplete comparison")
+		}
+		c := cond{path: strings.Split(toks[n], "."), op: toks[n+1]}
+		if !isFilterOp(c.op) {
+			return nil, fmt.Errorf("filter: unknown operator %q", c.op)
+		}
+		if c.val, err = parseFilterValue(toks[n+2]); err != nil {
+			return nil, err
+		}
+		f.conds = append(f.conds, c)
+
+		n += 3
+		if n < len(toks) {
+			if !strings.EqualFold(toks[n], "and") {
+				return nil, fmt.Errorf("filter: expected 'and', got %q", toks[n])
+			}
+			n++
+			if n == len(toks) {
+				return nil, errors.New("filter: trailing 'and'")
+			}
+		}
+	}
+	return f, nil
+}
+
+// Match Reports whether doc satisfies every comparison of f. A nil filter matches everything.
+func (f *Filter) Match(doc map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	for _, c := range f.conds {
+		v, ok := lookupPath(doc, c.path)
+		if !ok || !compare(v, c.op, c.val) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupPath(doc map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, p := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func compare(a interface{}, op string, b interface{}) bool {
+	switch bv := b.(type) {
+	case float64:
+		av, ok := a.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return av == bv
+		case "!=":
+			return av != bv
+		case ">":
+			return av > bv
+		case ">=":
+			return av >= bv
+		case "<":
+			return av < bv
+		case "<=":
+			return av <= bv
+		}
+	case string:
+		av, ok := a.(string)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return av == bv
+		case "!=":
+			return av != bv
+		case ">":
+			return av > bv
+		case ">=":
+			return av >= bv
+		case "<":
+			return av < bv
+		case "<=":
+			return av <= bv
+		}
+	default:
+		// bool and null only support equality.
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		}
+	}
+	return false
+}
+
+func isFilterOp(s string) bool {
+	for _, op := range filterOps {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFilterValue(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("filter: invalid value %q", s)
+	}
+	return n, nil
+}
+
+// lexFilter Splits expr into identifiers, operators and literals.
+func lexFilter(expr string) ([]string, error) {
+	var toks []string
+	rs := []rune(expr)
+	for n := 0; n < len(rs); {
+		switch r := rs[n]; {
+		case unicode.IsSpace(r):
+			n++
+		case r == '"':
+			end := n + 1
+			for ; end < len(rs) && rs[end] != '"'; end++ {
+				if rs[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(rs) {
+				return nil, errors.New("filter: unterminated string")
+			}
+			toks = append(toks, string(rs[n:end+1]))
+			n = end + 1
+		case strings.ContainsRune("=!<>", r):
+			end := n + 1
+			if end < len(rs) && rs[end] == '=' {
+				end++
+			}
+			toks = append(toks, string(rs[n:end]))
+			n = end
+		default:
+			end := n
+			for end < len(rs) && !unicode.IsSpace(rs[end]) && !strings.ContainsRune(`=!<>"`, rs[end]) {
+				end++
+			}
+			toks = append(toks, string(rs[n:end]))
+			n = end
+		}
+	}
+	return toks, nil
+}
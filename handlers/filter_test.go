@@ -0,0 +1,44 @@
+package handlers
+
+import "testing"
+
+// TestParseFilter Parsing and matching of filter expressions.
+func TestParseFilter(t *testing.T) {
+	doc := map[string]interface{}{
+		"name":    "Bruce",
+		"age":     float64(35),
+		"hero":    true,
+		"address": map[string]interface{}{"city": "Gotham City"},
+	}
+	tests := []struct {
+		name    string
+		expr    string
+		match   bool
+		wantErr bool
+	}{
+		{name: "Filter - Empty", expr: "", match: true},
+		{name: "Filter - String Equal", expr: `name == "Bruce"`, match: true},
+		{name: "Filter - String Not Equal", expr: `name != "Bruce"`, match: false},
+		{name: "Filter - Number Compare", expr: `age>=35 and age < 40`, match: true},
+		{name: "Filter - Bool", expr: `hero == true`, match: true},
+		{name: "Filter - Nested Path", expr: `address.city == "Gotham City"`, match: true},
+		{name: "Filter - Missing Field", expr: `alias == "Batman"`, match: false},
+		{name: "Filter - Type Mismatch", expr: `name > 3`, match: false},
+		{name: "Filter - Unknown Operator Failure", expr: `name = "Bruce"`, wantErr: true},
+		{name: "Filter - Incomplete Failure", expr: `name ==`, wantErr: true},
+		{name: "Filter - Trailing And Failure", expr: `age > 1 and`, wantErr: true},
+		{name: "Filter - Unterminated String Failure", expr: `name == "Bruce`, wantErr: true},
+		{name: "Filter - Invalid Value Failure", expr: `name == Bruce`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v want error %v", err, tt.wantErr)
+			}
+			if err == nil && f.Match(doc) != tt.match {
+				t.Errorf("got %v want %v", f.Match(doc), tt.match)
+			}
+		})
+	}
+}
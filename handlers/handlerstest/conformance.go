@@ -0,0 +1,226 @@
+// Package handlerstest checks that a router wiring of the resource handlers serves the gorest API as specified,
+// so every adapter behaves the same.
+package handlerstest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angarcia/gorest/handlers"
+)
+
+// Mount Returns a handler serving the Endpoints of rh under /api.
+type Mount func(rh *handlers.ResourceHandler) http.Handler
+
+// Conformance Runs the resource API conformance suite against the handler returned by mount. Each subtest
+// depends on the ones before it.
+func Conformance(t *testing.T, mount Mount) {
+	rh := handlers.CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableSoftDelete(time.Hour)
+	srv := httptest.NewServer(mount(rh))
+	defer srv.Close()
+	defer rh.Close()
+
+	s := &suite{url: srv.URL}
+	var id string
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{"CreateResource - Success", func(t *testing.T) {
+			resp, body := s.do(t, "POST", "/api/resources", `{"name":"Bruce","age":30}`, http.StatusCreated)
+			loc := resp.Header.Get("Location")
+			if !strings.HasPrefix(loc, "/api/resources/") {
+				t.Fatalf("got Location %q want /api/resources/{id}", loc)
+			}
+			id = strings.TrimPrefix(loc, "/api/resources/")
+			s.document(t, resp, body, map[string]interface{}{"name": "Bruce", "age": 30.0})
+		}},
+		{"CreateResource - Invalid Body Failure", func(t *testing.T) {
+			resp, body := s.do(t, "POST", "/api/resources", `{"name":`, http.StatusBadRequest)
+			s.error(t, resp, body, http.StatusBadRequest)
+		}},
+		{"GetResource - Success", func(t *testing.T) {
+			resp, body := s.do(t, "GET", "/api/resources/"+id, "", http.StatusOK)
+			s.document(t, resp, body, map[string]interface{}{"name": "Bruce", "age": 30.0})
+		}},
+		{"GetResource - Invalid ID Failure", func(t *testing.T) {
+			resp, body := s.do(t, "GET", "/api/resources/nope", "", http.StatusBadRequest)
+			s.error(t, resp, body, http.StatusBadRequest)
+		}},
+		{"GetResources - Success", func(t *testing.T) {
+			s.do(t, "POST", "/api/resources", `{"name":"Clark","age":35}`, http.StatusCreated)
+			_, body := s.do(t, "GET", "/api/resources", "", http.StatusOK)
+			if docs := s.documents(t, body); len(docs) != 2 {
+				t.Errorf("got %d resources want 2", len(docs))
+			}
+		}},
+		{"GetResources - Filter Success", func(t *testing.T) {
+			_, body := s.do(t, "GET", "/api/resources?filter=age+%3C+31", "", http.StatusOK)
+			if docs := s.documents(t, body); len(docs) != 1 || docs[id] == nil {
+				t.Errorf("got %v want only %s", docs, id)
+			}
+		}},
+		{"GetResources - Page Success", func(t *testing.T) {
+			resp, body := s.do(t, "GET", "/api/resources?limit=1", "", http.StatusOK)
+			if docs := s.documents(t, body); len(docs) != 1 {
+				t.Errorf("got %d resources want 1", len(docs))
+			}
+			if link := resp.Header.Get("Link"); !strings.HasPrefix(link, "</api/resources?") || !strings.HasSuffix(link, `>; rel="next"`) {
+				t.Errorf("got Link %q want the next page", link)
+			}
+		}},
+		{"GetResources - Invalid Filter Failure", func(t *testing.T) {
+			resp, body := s.do(t, "GET", "/api/resources?filter=age", "", http.StatusBadRequest)
+			s.error(t, resp, body, http.StatusBadRequest)
+		}},
+		{"UpdateResource - Success", func(t *testing.T) {
+			resp, body := s.do(t, "PUT", "/api/resources/"+id, `{"name":"Bruce Wayne","age":31}`, http.StatusAccepted)
+			s.document(t, resp, body, map[string]interface{}{"name": "Bruce Wayne", "age": 31.0})
+		}},
+		{"PatchResource - Success", func(t *testing.T) {
+			resp, body := s.do(t, "PATCH", "/api/resources/"+id, `{"age":null}`, http.StatusOK)
+			s.document(t, resp, body, map[string]interface{}{"name": "Bruce Wayne"})
+		}},
+		{"GetHistory - Success", func(t *testing.T) {
+			_, body := s.do(t, "GET", "/api/resources/"+id+"/history", "", http.StatusOK)
+			var revs []handlers.Revision
+			if err := json.Unmarshal(body, &revs); err != nil || len(revs) != 3 {
+				t.Errorf("got %s want 3 revisions", body)
+			}
+		}},
+		{"DeleteResource - Success", func(t *testing.T) {
+			s.do(t, "DELETE", "/api/resources/"+id, "", http.StatusNoContent)
+		}},
+		{"DeleteResource - Not Found Failure", func(t *testing.T) {
+			resp, body := s.do(t, "DELETE", "/api/resources/"+id, "", http.StatusBadRequest)
+			s.error(t, resp, body, http.StatusBadRequest)
+		}},
+		{"GetTrash - Success", func(t *testing.T) {
+			_, body := s.do(t, "GET", "/api/trash", "", http.StatusOK)
+			if !strings.Contains(string(body), id) {
+				t.Errorf("got %s want %s in trash", body, id)
+			}
+		}},
+		{"RestoreTrash - Success", func(t *testing.T) {
+			s.do(t, "POST", "/api/trash/"+id+"/restore", "", http.StatusCreated)
+			s.do(t, "GET", "/api/resources/"+id, "", http.StatusOK)
+		}},
+		{"ExecuteTransaction - Success", func(t *testing.T) {
+			body := `{"operations":[{"op":"patch","id":"` + id + `","if":"name == \"Bruce Wayne\"","document":{"age":32}},{"op":"read","id":"` + id + `"}]}`
+			_, b := s.do(t, "POST", "/api/transactions", body, http.StatusOK)
+			var res handlers.TransactionResult
+			if err := json.Unmarshal(b, &res); err != nil || len(res.Results) != 2 || res.Results[1].Document["age"] != 32.0 {
+				t.Errorf("got %s want the patched document read back", b)
+			}
+		}},
+		{"ExecuteTransaction - Precondition Failure", func(t *testing.T) {
+			body := `{"operations":[{"op":"delete","id":"` + id + `","ifVersion":1}]}`
+			resp, b := s.do(t, "POST", "/api/transactions", body, http.StatusPreconditionFailed)
+			s.error(t, resp, b, http.StatusPreconditionFailed)
+		}},
+		{"GetEvents - Success", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", s.url+"/api/resources/events", nil)
+			req.Header.Set("Last-Event-ID", "0")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+				t.Fatalf("got %d %q want %d text/event-stream", resp.StatusCode, ct, http.StatusOK)
+			}
+			sc := bufio.NewScanner(resp.Body)
+			for sc.Scan() {
+				if sc.Text() == "event: "+handlers.EventCreated {
+					return
+				}
+			}
+			t.Errorf("got no replayed %s event: %v", handlers.EventCreated, sc.Err())
+		}},
+	}
+	for _, tt := range tests {
+		if !t.Run(tt.name, tt.run) {
+			return
+		}
+	}
+}
+
+// suite Sends the conformance requests to the server at url.
+type suite struct {
+	url string
+}
+
+// do Sends a request with an optional JSON body and fails the test unless the response has status want.
+func (s *suite) do(t *testing.T, method, path, body string, want int) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: got %d want %d: %s", method, path, resp.StatusCode, want, b)
+	}
+	return resp, b
+}
+
+// document Checks that a response carries the JSON document want.
+func (s *suite) document(t *testing.T, resp *http.Response, body []byte, want map[string]interface{}) {
+	t.Helper()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("got Content-Type %q want application/json", ct)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("got %s: %v", body, err)
+	}
+	gb, _ := json.Marshal(got)
+	wb, _ := json.Marshal(want)
+	if string(gb) != string(wb) {
+		t.Errorf("got %s want %s", gb, wb)
+	}
+}
+
+// documents Decodes a collection, keyed by id.
+func (s *suite) documents(t *testing.T, body []byte) map[string]map[string]interface{} {
+	t.Helper()
+	docs := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(body, &docs); err != nil {
+		t.Fatalf("got %s: %v", body, err)
+	}
+	return docs
+}
+
+// error Checks that a response is rendered by CommonHandler.HttpError with the given status.
+func (s *suite) error(t *testing.T, resp *http.Response, body []byte, status int) {
+	t.Helper()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("got Content-Type %q want application/json; charset=utf-8", ct)
+	}
+	var e handlers.ErrorHttp
+	if err := json.Unmarshal(body, &e); err != nil || e.Status != status || e.Msg == "" {
+		t.Errorf("got %s want an error with status %d", body, status)
+	}
+}
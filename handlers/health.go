@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck Probes one dependency, returning an error that describes what is wrong with it.
+type HealthCheck func(ctx context.Context) error
+
+// CheckResult Outcome of one HealthCheck.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthStatus Body of /healthz, /readyz and /livez.
+type HealthStatus struct {
+	Status string                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// HealthHandler Reports to load balancers and orchestrators whether the server is alive, healthy and should
+// receive traffic.
+type HealthHandler struct {
+	ch      *CommonHandler
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks map[string]HealthCheck
+
+	// gate holds why the server is not ready, e.g. "starting" or "shutting down"; empty once ready.
+	gate atomic.Value
+}
+
+// CreateHealthHandler creation/initialization of health handler, not ready ("starting") until SetReady.
+func CreateHealthHandler() *HealthHandler {
+	hh := &HealthHandler{ch: &CommonHandler{}, timeout: 2 * time.Second, checks: make(map[string]HealthCheck)}
+	hh.gate.Store("starting")
+	return hh
+}
+
+// AddCheck Registers check under name, run by /healthz and, once the gate is open, /readyz.
+func (hh *HealthHandler) AddCheck(name string, check HealthCheck) {
+	hh.mu.Lock()
+	defer hh.mu.Unlock()
+	hh.checks[name] = check
+}
+
+// SetTimeout Bounds how long all checks of one probe may take together.
+func (hh *HealthHandler) SetTimeout(d time.Duration) {
+	hh.timeout = d
+}
+
+// SetReady Opens the readiness gate, e.g. once listening and done replaying stored state.
+func (hh *HealthHandler) SetReady() {
+	hh.gate.Store("")
+}
+
+// SetNotReady Closes the readiness gate for reason, e.g. "replaying" during startup or "shutting down".
+func (hh *HealthHandler) SetNotReady(reason string) {
+	hh.gate.Store(reason)
+}
+
+// run Runs every check concurrently and reports whether all passed. Checks still running when the timeout or ctx
+// ends, e.g. on a hung filesystem that ignores ctx, are reported as failed with "timeout" and left behind.
+func (hh *HealthHandler) run(ctx context.Context) (map[string]CheckResult, bool) {
+	hh.mu.Lock()
+	names := make([]string, 0, len(hh.checks))
+	for name := range hh.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]HealthCheck, len(names))
+	for i, name := range names {
+		checks[i] = hh.checks[name]
+	}
+	hh.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hh.timeout)
+	defer cancel()
+
+	type done struct {
+		i   int
+		res CheckResult
+	}
+	// Buffered so abandoned checks can still finish without blocking.
+	finished := make(chan done, len(checks))
+	start := time.Now()
+	for i, check := range checks {
+		go func(i int, check HealthCheck) {
+			begin := time.Now()
+			res := CheckResult{Status: "ok"}
+			if err := check(ctx); err != nil {
+				res.Status, res.Error = "fail", err.Error()
+			}
+			res.Duration = time.Since(begin).String()
+			finished <- done{i, res}
+		}(i, check)
+	}
+
+	results := make([]CheckResult, len(checks))
+	reported := make([]bool, len(checks))
+collect:
+	for range checks {
+		select {
+		case d := <-finished:
+			results[d.i], reported[d.i] = d.res, true
+		case <-ctx.Done():
+			break collect
+		}
+	}
+	for i := range results {
+		if !reported[i] {
+			results[i] = CheckResult{Status: "fail", Error: "timeout", Duration: time.Since(start).String()}
+		}
+	}
+
+	out, ok := make(map[string]CheckResult, len(names)), true
+	for i, name := range names {
+		out[name] = results[i]
+		ok = ok && results[i].Status == "ok"
+	}
+	return out, ok
+}
+
+// write Writes the probe body, never cached.
+func (hh *HealthHandler) write(w http.ResponseWriter, r *http.Request, hs HealthStatus, code int) {
+	data, err := hh.ch.Marshal(hs)
+	if err != nil {
+		hh.ch.logf(r, "error: %v", err)
+		hh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	_, err = w.Write(data)
+	if err != nil {
+		hh.ch.logf(r, "error: %v", err)
+	}
+}
+
+// GetLiveHandler GET /livez
+func (hh *HealthHandler) GetLiveHandler(w http.ResponseWriter, r *http.Request) {
+	hh.write(w, r, HealthStatus{Status: "alive"}, http.StatusOK)
+}
+
+// GetHealthHandler GET /healthz
+func (hh *HealthHandler) GetHealthHandler(w http.ResponseWriter, r *http.Request) {
+	checks, ok := hh.run(r.Context())
+	if !ok {
+		hh.write(w, r, HealthStatus{Status: "fail", Checks: checks}, http.StatusServiceUnavailable)
+		return
+	}
+	hh.write(w, r, HealthStatus{Status: "ok", Checks: checks}, http.StatusOK)
+}
+
+// GetReadyHandler GET /readyz
+func (hh *HealthHandler) GetReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if reason := hh.gate.Load().(string); reason != "" {
+		hh.write(w, r, HealthStatus{Status: "not ready", Reason: reason}, http.StatusServiceUnavailable)
+		return
+	}
+
+	checks, ok := hh.run(r.Context())
+	if !ok {
+		hh.write(w, r, HealthStatus{Status: "not ready", Reason: "check failed", Checks: checks}, http.StatusServiceUnavailable)
+		return
+	}
+	hh.write(w, r, HealthStatus{Status: "ready", Checks: checks}, http.StatusOK)
+}
+
+// DiskCheck Returns a check that dir is writable by creating, syncing and removing a file in it.
+func DiskCheck(dir string) HealthCheck {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".gorest-health-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err = f.WriteString(time.Now().UTC().Format(time.RFC3339Nano)); err == nil {
+			err = f.Sync()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHealthHandler_GetReadyHandler GET /readyz
+func TestHealthHandler_GetReadyHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		ready  bool
+		reason string
+		check  error
+		want   int
+		body   string
+	}{
+		{name: "GetReady - Ready Success", ready: true, want: 200, body: `"ready"`},
+		{name: "GetReady - Starting Failure", want: 503, body: `"starting"`},
+		{name: "GetReady - Shutting Down Failure", reason: "shutting down", want: 503, body: `"shutting down"`},
+		{name: "GetReady - Check Failure", ready: true, check: errors.New("wedged"), want: 503, body: `"wedged"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hh := CreateHealthHandler()
+			hh.AddCheck("store", func(ctx context.Context) error { return tt.check })
+			if tt.ready {
+				hh.SetReady()
+			}
+			if tt.reason != "" {
+				hh.SetNotReady(tt.reason)
+			}
+			r, _ := http.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+
+			hh.GetReadyHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body %q missing %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+// TestHealthHandler_GetHealthHandler GET /healthz
+func TestHealthHandler_GetHealthHandler(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	// hung blocks a check that ignores its context, like a stuck filesystem call, until the test ends.
+	hung := make(chan struct{})
+	defer close(hung)
+	tests := []struct {
+		name   string
+		checks map[string]HealthCheck
+		want   int
+		body   []string
+	}{
+		{name: "GetHealth - No Checks Success", want: 200, body: []string{`"ok"`}},
+		{name: "GetHealth - Store And Disk Success", checks: map[string]HealthCheck{"store": rh.Ping, "disk": DiskCheck(t.TempDir())},
+			want: 200, body: []string{`"store":{"status":"ok"`, `"disk":{"status":"ok"`}},
+		{name: "GetHealth - Disk Failure", checks: map[string]HealthCheck{"store": rh.Ping, "disk": DiskCheck(filepath.Join(t.TempDir(), "missing"))},
+			want: 503, body: []string{`"fail"`, `"store":{"status":"ok"`}},
+		{name: "GetHealth - Timeout Failure", checks: map[string]HealthCheck{"slow": func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }},
+			want: 503, body: []string{`"slow":{"status":"fail"`}},
+		{name: "GetHealth - Hung Check Timeout Failure", checks: map[string]HealthCheck{"store": rh.Ping, "hung": func(ctx context.Context) error { <-hung; return nil }},
+			want: 503, body: []string{`"hung":{"status":"fail","error":"timeout"`, `"store":{"status":"ok"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hh := CreateHealthHandler()
+			hh.SetTimeout(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				hh.AddCheck(name, check)
+			}
+			r, _ := http.NewRequest("GET", "/healthz", nil)
+			w := httptest.NewRecorder()
+
+			hh.GetHealthHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			for _, b := range tt.body {
+				if !strings.Contains(w.Body.String(), b) {
+					t.Errorf("body %q missing %q", w.Body.String(), b)
+				}
+			}
+		})
+	}
+}
+
+// TestResourceHandler_Ping A store held locked is reported unavailable.
+func TestResourceHandler_Ping(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	if err := rh.Ping(context.Background()); err != nil {
+		t.Fatalf("got %v want nil", err)
+	}
+
+	rh.dh.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := rh.Ping(ctx)
+	rh.dh.mu.Unlock()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v want deadline exceeded", err)
+	}
+}
@@ -1,9 +1,156 @@
 package handlers
 
-import "sync"
+import (
+	"sync"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+)
+
+// defaultHistorySize Number of revisions kept per resource when DBHelper.histSize is unset.
+const defaultHistorySize = 32
 
 // DBHelper Definition of main map as well mutex configuration lock/unlock data for multiple concurrent requests.
 type DBHelper struct {
-	db map[string]map[string]interface{}
+	db       map[string]map[string]interface{}
+	hist     map[string][]Revision
+	histSize int
+
+	// Soft delete: removed resources wait in trash until restored, purged or past retention.
+	soft      bool
+	retention time.Duration
+	trash     map[string]Trashed
+
+	// Expiry: resources past exp are hidden from reads and reaped by the sweeper.
+	exp map[string]time.Time
+	ttl time.Duration
+
+	// Ownership: documents carry their creator and access is limited to it, its groups and adminRole.
+	owned     bool
+	adminRole string
+
+	// Tenant quota: the most live resources the store accepts, unlimited when zero.
+	maxResources int
+
+	// Rate limit buckets of every limiter using ResourceHandler.Buckets, keyed by client and rule.
+	buckets map[string]*mw.Bucket
+
+	// Instrumentation, set once by ResourceHandler.Instrument before serving.
+	lockWait observer
+	payload  observer
+
 	mu sync.Mutex
 }
+
+// observer Subset of prometheus.Observer used by the store.
+type observer interface {
+	Observe(float64)
+}
+
+// lock Acquires mu, recording how long the caller waited when instrumented.
+func (dh *DBHelper) lock() {
+	if dh.lockWait == nil {
+		dh.mu.Lock()
+		return
+	}
+	start := time.Now()
+	dh.mu.Lock()
+	dh.lockWait.Observe(time.Since(start).Seconds())
+}
+
+// Revision A stored state of a resource. Deleted revisions mark the point a resource was removed.
+type Revision struct {
+	Version  int                    `json:"version"`
+	Time     time.Time              `json:"time"`
+	Deleted  bool                   `json:"deleted,omitempty"`
+	Document map[string]interface{} `json:"document,omitempty"`
+}
+
+// record Appends a revision of resource i to its bounded history and returns the new version.
+// Caller must hold mu.
+func (dh *DBHelper) record(i string, doc map[string]interface{}, deleted bool) int {
+	if dh.hist == nil {
+		dh.hist = make(map[string][]Revision)
+	}
+	size := dh.histSize
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+
+	h := dh.hist[i]
+	v := 1
+	if len(h) > 0 {
+		v = h[len(h)-1].Version + 1
+	}
+	h = append(h, Revision{Version: v, Time: time.Now().UTC(), Deleted: deleted, Document: doc})
+	if len(h) > size {
+		h = append([]Revision(nil), h[len(h)-size:]...)
+	}
+	dh.hist[i] = h
+	return v
+}
+
+// remove Deletes resource i, into the trash with soft delete, and returns the version recording the deletion.
+// A hard delete drops the history of i too, since no revision of it remains reachable through the store.
+// Caller must hold mu.
+func (dh *DBHelper) remove(i string) int {
+	delete(dh.exp, i)
+	if dh.soft {
+		dh.moveToTrash(i)
+		return dh.record(i, nil, true)
+	}
+	v := dh.version(i) + 1
+	delete(dh.db, i)
+	delete(dh.hist, i)
+	return v
+}
+
+// version Returns the latest version of resource i, zero when it has no history. Caller must hold mu.
+func (dh *DBHelper) version(i string) int {
+	if h := dh.hist[i]; len(h) > 0 {
+		return h[len(h)-1].Version
+	}
+	return 0
+}
+
+// revision Returns the revision of resource i with the given version. Caller must hold mu.
+func (dh *DBHelper) revision(i string, v int) (Revision, bool) {
+	for _, r := range dh.hist[i] {
+		if r.Version == v {
+			return r, true
+		}
+	}
+	return Revision{}, false
+}
+
+// revisionAt Returns the revision of resource i in effect at t. Caller must hold mu.
+func (dh *DBHelper) revisionAt(i string, t time.Time) (Revision, bool) {
+	h := dh.hist[i]
+	for n := len(h) - 1; n >= 0; n-- {
+		if !h[n].Time.After(t) {
+			return h[n], true
+		}
+	}
+	return Revision{}, false
+}
+
+// mergePatch Returns a copy of doc with the JSON merge patch applied, leaving doc itself untouched since it is
+// shared with the revision history.
+func mergePatch(doc, patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc)+len(patch))
+	for k, v := range doc {
+		out[k] = v
+	}
+	for k, v := range patch {
+		switch pv := v.(type) {
+		case nil:
+			delete(out, k)
+		case map[string]interface{}:
+			dv, _ := out[k].(map[string]interface{})
+			out[k] = mergePatch(dv, pv)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SetHistorySize Keeps the latest n revisions of each resource. Zero keeps the default of 32.
+func (rh *ResourceHandler) SetHistorySize(n int) {
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	rh.dh.histSize = n
+}
+
+// findRevision Resolves a revision of resource i by version number or RFC 3339 timestamp.
+// The returned status code is meaningful only when err is not nil. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) findRevision(i, version, asOf string) (Revision, int, error) {
+	if err := rh.idPolicy().Validate(i); err != nil {
+		return Revision{}, http.StatusBadRequest, err
+	}
+
+	var rev Revision
+	var ok bool
+	if version != "" {
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			return Revision{}, http.StatusBadRequest, errors.New("version must be an integer")
+		}
+		rev, ok = rh.dh.revision(i, v)
+	} else {
+		t, err := time.Parse(time.RFC3339Nano, asOf)
+		if err != nil {
+			return Revision{}, http.StatusBadRequest, errors.New("asOf must be an RFC 3339 timestamp")
+		}
+		rev, ok = rh.dh.revisionAt(i, t)
+	}
+	if !ok || rh.dh.expired(i, time.Now()) {
+		return Revision{}, http.StatusNotFound, errors.New("the requested revision does not exist or is no longer retained")
+	}
+	return rev, 0, nil
+}
+
+// getRevision GET /api/resources/{id}?version=N | ?asOf=timestamp. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) getRevision(w http.ResponseWriter, r *http.Request, i, version, asOf string) {
+	rev, code, err := rh.findRevision(i, version, asOf)
+	if err == nil && rev.Deleted {
+		code, err = http.StatusNotFound, errors.New("the resource was deleted at the requested revision")
+	}
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
+		return
+	}
+	if !rh.checkAccess(w, r, rh.dh.document(i)) {
+		return
+	}
+
+	data, err := rh.ch.Marshal(rev.Document)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.Header().Set("X-Resource-Version", strconv.Itoa(rev.Version))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+
+	rh.ch.logf(r, "Resource Revision Returned: %v Version: %v\n", i, rev.Version)
+}
+
+// GetHistoryHandler GET /api/resources/{id}/history
+func (rh *ResourceHandler) GetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	i := mux.Vars(r)["id"]
+	if err := rh.idPolicy().Validate(i); err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h, ok := rh.dh.hist[i]
+	if !ok || rh.dh.expired(i, time.Now()) {
+		rh.ch.logf(r, "error: no history for %v", i)
+		rh.ch.HttpError(w, "the id provided has no recorded history", http.StatusNotFound)
+		return
+	}
+	if !rh.checkAccess(w, r, rh.dh.document(i)) {
+		return
+	}
+
+	data, err := rh.ch.Marshal(h)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+
+	rh.ch.logf(r, "Resource History Returned: %v Revisions: %v\n", i, len(h))
+}
+
+// RestoreResourceHandler POST /api/resources/{id}/restore?version=N
+// Writes the document of the given revision as a new revision of a live or trashed resource, taking a trashed one
+// out of the trash. Hard-deleted resources keep no history and cannot be restored.
+// A resource restored from the trash gets the default TTL like a created one; a live one keeps its expiry.
+func (rh *ResourceHandler) RestoreResourceHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	defer r.Body.Close()
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	i := mux.Vars(r)["id"]
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		rh.ch.HttpError(w, "version is required", http.StatusBadRequest)
+		return
+	}
+	rev, code, err := rh.findRevision(i, version, "")
+	if err == nil && rev.Deleted {
+		code, err = http.StatusBadRequest, errors.New("cannot restore a deletion revision")
+	}
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
+		return
+	}
+	if !rh.checkAccess(w, r, rh.dh.document(i)) {
+		return
+	}
+
+	t, code := EventUpdated, http.StatusAccepted
+	if _, ok := rh.dh.db[i]; !ok {
+		if !rh.checkQuota(w, r) {
+			return
+		}
+		t, code = EventCreated, http.StatusCreated
+	}
+	rh.dh.db[i] = rev.Document
+	delete(rh.dh.trash, i)
+	v := rh.dh.record(i, rev.Document, false)
+	rh.publish(t, i, v, rev.Document)
+	rh.applyTTL(w, i, rh.dh.ttl, t == EventCreated)
+
+	data, err := rh.ch.Marshal(rev.Document)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.Header().Set("X-Resource-Version", strconv.Itoa(v))
+	w.WriteHeader(code)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+
+	rh.ch.logf(r, "Resource Restored: %v From Version: %v\n", i, rev.Version)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// historyHandler Returns a handler whose resource went through create, update and delete.
+func historyHandler() (*ResourceHandler, time.Time) {
+	i := "0bf8651a-0923-47b8-aed3-e9fc1505e497"
+	rh := &ResourceHandler{
+		ch: &CommonHandler{},
+		dh: &DBHelper{
+			db: map[string]map[string]interface{}{},
+			mu: sync.Mutex{},
+		},
+	}
+	rh.dh.db[i] = map[string]interface{}{"name": "Clark"}
+	rh.dh.record(i, rh.dh.db[i], false)
+	rh.dh.hist[i][0].Time = time.Now().Add(-time.Hour)
+	rh.dh.db[i] = map[string]interface{}{"name": "Bruce"}
+	rh.dh.record(i, rh.dh.db[i], false)
+	delete(rh.dh.db, i)
+	rh.dh.record(i, nil, true)
+	return rh, time.Now().Add(-30 * time.Minute)
+}
+
+// TestResourceHandler_GetHistoryHandler GET /api/resources/{id}/history and ?version= / ?asOf= reads
+func TestResourceHandler_GetHistoryHandler(t *testing.T) {
+	rh, between := historyHandler()
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		query   string
+		want    int
+		body    string
+	}{
+		{name: "GetHistory - Success", handler: rh.GetHistoryHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200, body: `"deleted":true`},
+		{name: "GetHistory - Invalid UUID Failure", handler: rh.GetHistoryHandler, id: "dummy", want: 400},
+		{name: "GetHistory - UUID Not Exist Failure", handler: rh.GetHistoryHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e496", want: 404},
+		{name: "GetResource - Version Success", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?version=2", want: 200, body: "Bruce"},
+		{name: "GetResource - AsOf Success", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?asOf=" + between.Format(time.RFC3339Nano), want: 200, body: "Clark"},
+		{name: "GetResource - Deleted Version Failure", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?version=3", want: 404},
+		{name: "GetResource - Unknown Version Failure", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?version=9", want: 404},
+		{name: "GetResource - Invalid Version Failure", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?version=dummy", want: 400},
+		{name: "GetResource - Invalid AsOf Failure", handler: rh.GetResourceHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", query: "?asOf=yesterday", want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/"+tt.query, nil)
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			tt.handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body %q missing %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+// TestResourceHandler_RestoreResourceHandler POST /api/resources/{id}/restore
+func TestResourceHandler_RestoreResourceHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		doc   string
+	}{
+		{name: "RestoreResource - Recreate Success", query: "?version=1", want: 201, doc: "Clark"},
+		{name: "RestoreResource - Deletion Revision Failure", query: "?version=3", want: 400},
+		{name: "RestoreResource - Missing Version Failure", query: "", want: 400},
+		{name: "RestoreResource - Unknown Version Failure", query: "?version=9", want: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh, _ := historyHandler()
+			r, _ := http.NewRequest("POST", "/"+tt.query, strings.NewReader(``))
+			r = mux.SetURLVars(r, map[string]string{"id": "0bf8651a-0923-47b8-aed3-e9fc1505e497"})
+			w := httptest.NewRecorder()
+
+			rh.RestoreResourceHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if tt.doc != "" && rh.dh.db["0bf8651a-0923-47b8-aed3-e9fc1505e497"]["name"] != tt.doc {
+				t.Errorf("got %v want %v", rh.dh.db["0bf8651a-0923-47b8-aed3-e9fc1505e497"], tt.doc)
+			}
+			if tt.doc != "" && w.Header().Get("X-Resource-Version") != "4" {
+				t.Errorf("got version %q want %q", w.Header().Get("X-Resource-Version"), "4")
+			}
+		})
+	}
+}
+
+// TestDBHelper_record History is bounded per resource.
+func TestDBHelper_record(t *testing.T) {
+	dh := &DBHelper{histSize: 2}
+	for n := 0; n < 5; n++ {
+		dh.record("0bf8651a-0923-47b8-aed3-e9fc1505e497", nil, false)
+	}
+	h := dh.hist["0bf8651a-0923-47b8-aed3-e9fc1505e497"]
+	if len(h) != 2 || h[0].Version != 4 || h[1].Version != 5 {
+		t.Errorf("got %+v want versions 4 and 5", h)
+	}
+}
+
+// TestDBHelper_remove A hard delete drops the history of the resource; a soft delete keeps it for restores.
+func TestDBHelper_remove(t *testing.T) {
+	tests := []struct {
+		name    string
+		soft    bool
+		version int
+		hist    int
+	}{
+		{name: "Remove - Hard Delete Success", version: 2, hist: 0},
+		{name: "Remove - Soft Delete Success", soft: true, version: 2, hist: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := "0bf8651a-0923-47b8-aed3-e9fc1505e497"
+			dh := &DBHelper{db: map[string]map[string]interface{}{i: {"name": "Bruce"}}, soft: tt.soft}
+			dh.record(i, dh.db[i], false)
+			dh.setTTL(i, time.Hour)
+
+			if v := dh.remove(i); v != tt.version {
+				t.Errorf("got version %d want %d", v, tt.version)
+			}
+			if _, ok := dh.db[i]; ok {
+				t.Errorf("got %v want deleted", dh.db[i])
+			}
+			if _, ok := dh.exp[i]; ok {
+				t.Errorf("got expiry %v want none", dh.exp[i])
+			}
+			if n := len(dh.hist[i]); n != tt.hist {
+				t.Errorf("got %d revisions want %d", n, tt.hist)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDPolicy Generates the ids of created resources and rejects malformed ids in request paths.
+// Generated ids must be unique and safe to use as a URL path segment.
+type IDPolicy interface {
+	NewID() string
+	Validate(id string) error
+}
+
+// UUIDs The default IDPolicy: random UUIDs.
+var UUIDs IDPolicy = uuidPolicy{}
+
+type uuidPolicy struct{}
+
+// NewID Implements IDPolicy.
+func (uuidPolicy) NewID() string {
+	return uuid.New().String()
+}
+
+// Validate Implements IDPolicy.
+func (uuidPolicy) Validate(id string) error {
+	_, err := uuid.Parse(id)
+	return err
+}
+
+// SetIDPolicy Makes rh generate and accept ids with p instead of UUIDs.
+// It must be called before rh starts serving requests.
+func (rh *ResourceHandler) SetIDPolicy(p IDPolicy) {
+	rh.ids = p
+}
+
+// idPolicy Returns rh's id policy, UUIDs when unset.
+func (rh *ResourceHandler) idPolicy() IDPolicy {
+	if rh.ids == nil {
+		return UUIDs
+	}
+	return rh.ids
+}
+
+// checkID Like CheckID, validating i with rh's id policy. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) checkID(i string) error {
+	if err := rh.idPolicy().Validate(i); err != nil {
+		return err
+	}
+	if _, ok := rh.dh.db[i]; !ok || rh.dh.expired(i, time.Now()) {
+		return errors.New("the id provided does not exist in database")
+	}
+	return nil
+}
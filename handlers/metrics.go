@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Instrument Registers store-level metrics for rh on reg: resource counts, lock wait time and payload sizes.
+// It must be called before rh starts serving requests.
+func (rh *ResourceHandler) Instrument(reg prometheus.Registerer) error {
+	count := func(collection string, n func(dh *DBHelper) int) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "gorest_store_resources",
+			Help:        "Number of resources held by the store.",
+			ConstLabels: prometheus.Labels{"collection": collection},
+		}, func() float64 {
+			total := 0
+			for _, h := range rh.all() {
+				h.dh.mu.Lock()
+				total += n(h.dh)
+				h.dh.mu.Unlock()
+			}
+			return float64(total)
+		})
+	}
+	lockWait := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gorest_store_lock_wait_seconds",
+		Help:    "Time spent waiting to acquire the store lock.",
+		Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+	})
+	payload := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gorest_store_payload_bytes",
+		Help:    "Size of resource documents written to the store.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	})
+
+	for _, c := range []prometheus.Collector{
+		count("resources", func(dh *DBHelper) int { return len(dh.db) }),
+		count("trash", func(dh *DBHelper) int { return len(dh.trash) }),
+		lockWait,
+		payload,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	rh.dh.lockWait = lockWait
+	rh.dh.payload = payload
+	return nil
+}
+
+// observePayload Records the size of a written document. Safe without mu: payload is set once before serving and
+// observers are safe for concurrent use.
+func (dh *DBHelper) observePayload(n int) {
+	if dh.payload != nil {
+		dh.payload.Observe(float64(n))
+	}
+}
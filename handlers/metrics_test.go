@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestResourceHandler_Instrument Store gauges and histograms follow writes.
+func TestResourceHandler_Instrument(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rh := CreateHandler(map[string]map[string]interface{}{})
+	if err := rh.Instrument(reg); err != nil {
+		t.Fatal(err)
+	}
+	if err := rh.Instrument(reg); err == nil {
+		t.Errorf("registering twice should fail")
+	}
+
+	r, _ := http.NewRequest("POST", "", strings.NewReader(`{"name":"Bruce","lastname":"Wayne"}`))
+	rh.CreateResourceHandler(httptest.NewRecorder(), r)
+
+	want := `
+# HELP gorest_store_resources Number of resources held by the store.
+# TYPE gorest_store_resources gauge
+gorest_store_resources{collection="resources"} 1
+gorest_store_resources{collection="trash"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "gorest_store_resources"); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"gorest_store_lock_wait_seconds", "gorest_store_payload_bytes"} {
+		if n := testutil.CollectAndCount(reg, name); n != 1 {
+			t.Errorf("%s: got %d series want %d", name, n, 1)
+		}
+	}
+}
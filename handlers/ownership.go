@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/angarcia/gorest/mw"
+)
+
+// Reserved document fields recording the creating principal and the groups sharing access to a resource.
+const (
+	ownerField  = "_owner"
+	groupsField = "_groups"
+)
+
+// EnableOwnership Records the creating principal on every new resource and restricts reads and writes to its owner,
+// principals in one of the groups listed in its _groups field, and principals holding adminRole.
+// Resources without an owner, such as those created anonymously, stay accessible to everyone.
+func (rh *ResourceHandler) EnableOwnership(adminRole string) {
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	rh.dh.owned = true
+	rh.dh.adminRole = adminRole
+}
+
+// canAccess Reports whether the principal of r may read or write doc.
+func (rh *ResourceHandler) canAccess(r *http.Request, doc map[string]interface{}) bool {
+	return rh.CanAccess(mw.PrincipalFrom(r.Context()), doc)
+}
+
+// CanAccess Reports whether p, nil when anonymous, may read or write doc under the ownership rules of rh, e.g. to
+// filter what a WebhookDispatcher delivers.
+func (rh *ResourceHandler) CanAccess(p *mw.Principal, doc map[string]interface{}) bool {
+	if !rh.dh.owned {
+		return true
+	}
+	owner, _ := doc[ownerField].(string)
+	if owner == "" {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	if p.Subject == owner || (rh.dh.adminRole != "" && p.HasRole(rh.dh.adminRole)) {
+		return true
+	}
+	groups, _ := doc[groupsField].([]interface{})
+	for _, g := range groups {
+		for _, pg := range p.Groups {
+			if g == pg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkAccess Replies 403 unless the principal of r may access doc.
+func (rh *ResourceHandler) checkAccess(w http.ResponseWriter, r *http.Request, doc map[string]interface{}) bool {
+	if rh.canAccess(r, doc) {
+		return true
+	}
+	rh.ch.logf(r, "error: access to resource owned by %v denied", doc[ownerField])
+	rh.ch.HttpError(w, "the resource belongs to another principal", http.StatusForbidden)
+	return false
+}
+
+// own Sets the owner of a document about to be written over old (nil on create). The owner of an existing
+// resource is kept; only admins may assign one explicitly.
+func (rh *ResourceHandler) own(r *http.Request, obj, old map[string]interface{}) {
+	if !rh.dh.owned {
+		return
+	}
+	p := mw.PrincipalFrom(r.Context())
+	if _, ok := obj[ownerField].(string); ok && p != nil && rh.dh.adminRole != "" && p.HasRole(rh.dh.adminRole) {
+		return
+	}
+
+	delete(obj, ownerField)
+	if old != nil {
+		if o, ok := old[ownerField]; ok {
+			obj[ownerField] = o
+		}
+		return
+	}
+	if p != nil {
+		obj[ownerField] = p.Subject
+	}
+}
+
+// document Returns the latest known document of resource i, live, trashed or from history, for access checks.
+// Caller must hold mu.
+func (dh *DBHelper) document(i string) map[string]interface{} {
+	if doc, ok := dh.db[i]; ok {
+		return doc
+	}
+	if t, ok := dh.trash[i]; ok {
+		return t.Document
+	}
+	h := dh.hist[i]
+	for n := len(h) - 1; n >= 0; n-- {
+		if !h[n].Deleted {
+			return h[n].Document
+		}
+	}
+	return nil
+}
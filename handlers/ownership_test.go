@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/gorilla/mux"
+)
+
+// ownedHandler Returns an ownership-enforcing handler holding one resource owned by bruce and shared with group
+// "league", and one resource without owner.
+func ownedHandler() *ResourceHandler {
+	rh := &ResourceHandler{
+		ch: &CommonHandler{},
+		dh: &DBHelper{
+			db: map[string]map[string]interface{}{
+				"0bf8651a-0923-47b8-aed3-e9fc1505e497": {"name": "Bruce", ownerField: "bruce", groupsField: []interface{}{"league"}},
+				"0bf8651a-0923-47b8-aed3-e9fc1505e496": {"name": "Public"},
+			},
+			mu: sync.Mutex{},
+		},
+	}
+	rh.EnableOwnership("admin")
+	return rh
+}
+
+// asPrincipal Returns r carrying a principal, or r unchanged for an empty subject.
+func asPrincipal(r *http.Request, subject string, roles, groups []string) *http.Request {
+	if subject == "" {
+		return r
+	}
+	return r.WithContext(mw.WithPrincipal(r.Context(), &mw.Principal{Subject: subject, Roles: roles, Groups: groups}))
+}
+
+// TestResourceHandler_Ownership Owner, group and admin access on reads, writes and deletes.
+func TestResourceHandler_Ownership(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(rh *ResourceHandler) http.HandlerFunc
+		method  string
+		id      string
+		subject string
+		roles   []string
+		groups  []string
+		want    int
+	}{
+		{name: "GetResource - Owner Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "bruce", want: 200},
+		{name: "GetResource - Group Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "clark", groups: []string{"league"}, want: 200},
+		{name: "GetResource - Admin Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "diana", roles: []string{"admin"}, want: 200},
+		{name: "GetResource - Other Principal Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "joker", want: 403},
+		{name: "GetResource - Anonymous Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 403},
+		{name: "GetResource - Unowned Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetResourceHandler }, method: "GET", id: "0bf8651a-0923-47b8-aed3-e9fc1505e496", want: 200},
+		{name: "UpdateResource - Other Principal Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.UpdateResourceHandler }, method: "PUT", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "joker", want: 403},
+		{name: "UpdateResource - Group Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.UpdateResourceHandler }, method: "PUT", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "clark", groups: []string{"league"}, want: 202},
+		{name: "DeleteResource - Other Principal Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.DeleteResourceHandler }, method: "DELETE", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "joker", want: 403},
+		{name: "DeleteResource - Owner Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.DeleteResourceHandler }, method: "DELETE", id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", subject: "bruce", want: 204},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := ownedHandler()
+			r, _ := http.NewRequest(tt.method, "/", strings.NewReader(`{"name":"Changed","_owner":"joker"}`))
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			r = asPrincipal(r, tt.subject, tt.roles, tt.groups)
+			w := httptest.NewRecorder()
+
+			tt.handler(rh)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if tt.method == "PUT" && rh.dh.db[tt.id][ownerField] != "bruce" {
+				t.Errorf("got owner %v want %v", rh.dh.db[tt.id][ownerField], "bruce")
+			}
+		})
+	}
+}
+
+// TestResourceHandler_OwnershipCreateList Created resources record their owner and lists hide foreign resources.
+func TestResourceHandler_OwnershipCreateList(t *testing.T) {
+	rh := ownedHandler()
+
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name":"Joker","_owner":"bruce"}`))
+	w := httptest.NewRecorder()
+	rh.CreateResourceHandler(w, asPrincipal(r, "joker", nil, nil))
+	if w.Code != 201 {
+		t.Fatalf("got %d want %d", w.Code, 201)
+	}
+	if !strings.Contains(w.Body.String(), `"_owner":"joker"`) {
+		t.Errorf("body %q missing owner joker", w.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	rh.GetResourcesHandler(w, asPrincipal(r, "joker", nil, nil))
+	if body := w.Body.String(); strings.Contains(body, "Bruce") || !strings.Contains(body, "Joker") || !strings.Contains(body, "Public") {
+		t.Errorf("got %s want Joker and Public only", body)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	rh.GetResourcesHandler(w, asPrincipal(r, "diana", []string{"admin"}, nil))
+	if body := w.Body.String(); !strings.Contains(body, "Bruce") || !strings.Contains(body, "Joker") {
+		t.Errorf("got %s want every resource", body)
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+)
+
+// Buckets Returns a rate limit BucketStore keeping its buckets in rh's store rather than in the limiter, so every
+// limiter built on it draws from one bucket per client and rule. Idle buckets are dropped by the sweeper.
+func (rh *ResourceHandler) Buckets() mw.BucketStore {
+	return storeBuckets{dh: rh.dh}
+}
+
+// storeBuckets BucketStore backed by a DBHelper.
+type storeBuckets struct {
+	dh *DBHelper
+}
+
+// Take Implements mw.BucketStore.
+func (s storeBuckets) Take(key string, limit mw.RateLimit, now time.Time) (bool, int, time.Duration) {
+	s.dh.lock()
+	defer s.dh.mu.Unlock()
+
+	if s.dh.buckets == nil {
+		s.dh.buckets = make(map[string]*mw.Bucket)
+	}
+	b, ok := s.dh.buckets[key]
+	if !ok {
+		b = mw.NewBucket(limit, now)
+		s.dh.buckets[key] = b
+	}
+	return b.Take(limit, now)
+}
+
+// dropIdleBuckets Removes the rate limit buckets that have refilled completely by now. Caller must hold mu.
+func (dh *DBHelper) dropIdleBuckets(now time.Time) {
+	for k, b := range dh.buckets {
+		if b.Idle(now) {
+			delete(dh.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+)
+
+// TestResourceHandler_Buckets Two limiters on the same store draw from one bucket per client.
+func TestResourceHandler_Buckets(t *testing.T) {
+	rh := CreateHandler(map[string]map[string]interface{}{})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	opts := mw.RateLimitOptions{Default: mw.RateLimit{Rate: 0.001, Burst: 2}, Store: rh.Buckets()}
+	first, second := mw.RateLimiter(opts)(ok), mw.RateLimiter(opts)(ok)
+
+	tests := []struct {
+		name    string
+		limiter http.Handler
+		addr    string
+		want    int
+	}{
+		{name: "Buckets - First Limiter", limiter: first, addr: "10.0.0.1:1000", want: 200},
+		{name: "Buckets - Second Limiter", limiter: second, addr: "10.0.0.1:1001", want: 200},
+		{name: "Buckets - First Limiter Exhausted", limiter: first, addr: "10.0.0.1:1002", want: 429},
+		{name: "Buckets - Second Limiter Exhausted", limiter: second, addr: "10.0.0.1:1003", want: 429},
+		{name: "Buckets - Other Client", limiter: second, addr: "10.0.0.2:1000", want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/resources", nil)
+			r.RemoteAddr = tt.addr
+			w := httptest.NewRecorder()
+			tt.limiter.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	rh.sweep(time.Now().Add(time.Hour))
+	if n := len(rh.dh.buckets); n != 0 {
+		t.Errorf("got %d buckets after sweep want %d", n, 0)
+	}
+}
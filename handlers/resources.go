@@ -1,19 +1,33 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // ResourceHandler contains resource handler data
 type ResourceHandler struct {
-	ch *CommonHandler
-	dh *DBHelper
+	ch  *CommonHandler
+	dh  *DBHelper
+	eb  *EventBroker
+	tr  trace.Tracer
+	ids IDPolicy
+
+	// Tenancy: tenants is shared with tenant-scoped copies, which name their tenant.
+	tenant  string
+	tenants *tenants
 }
 
 // CreateHandler creation/initialization of resource handler.
@@ -24,9 +38,44 @@ func CreateHandler(db map[string]map[string]interface{}) *ResourceHandler {
 			db: db,
 			mu: sync.Mutex{},
 		},
+		eb: CreateEventBroker(1024, 64),
 	}
 }
 
+// Events Returns the broker receiving every change made through rh.
+func (rh *ResourceHandler) Events() *EventBroker {
+	return rh.eb
+}
+
+// Close Ends rh's event streams so in-flight SSE and WebSocket requests return during shutdown.
+// The store is held in memory and needs no flushing.
+func (rh *ResourceHandler) Close() {
+	rh.eb.Close()
+}
+
+// Ping Implements HealthCheck: every store, tenants included, can be locked before ctx ends, so none is wedged
+// by a stuck request.
+func (rh *ResourceHandler) Ping(ctx context.Context) error {
+	for _, h := range rh.all() {
+		locked := make(chan struct{})
+		go func(dh *DBHelper) {
+			dh.mu.Lock()
+			dh.mu.Unlock()
+			close(locked)
+		}(h.dh)
+
+		select {
+		case <-locked:
+		case <-ctx.Done():
+			if h.tenant != "" {
+				return fmt.Errorf("store of tenant %s unavailable: %w", h.tenant, ctx.Err())
+			}
+			return fmt.Errorf("store unavailable: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
 // CheckID | The functions allows the check if a correct key string was provided is correct and if so check is exists.
 func CheckID(i string, db map[string]map[string]interface{}) error {
 
@@ -41,190 +90,307 @@ func CheckID(i string, db map[string]map[string]interface{}) error {
 	return errors.New("the id provided does not exist in database")
 }
 
-// GetResourcesHandler GET /api/resources/
+// GetResourcesHandler GET /api/resources/?filter=expr&limit=N&after=id
+// With a limit, resources are returned in id order and a Link header with rel="next" points to the next page.
 func (rh *ResourceHandler) GetResourcesHandler(w http.ResponseWriter, r *http.Request) {
-	rh.dh.mu.Lock()
-	defer rh.dh.mu.Unlock()
-
-	if len(rh.dh.db) > 0 {
-		data, err := rh.ch.Marshal(rh.dh.db)
-		if err != nil {
-			log.Printf("error: %v", err)
-			rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	q := r.URL.Query()
+	filter, err := ParseFilter(q.Get("filter"))
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if l := q.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			rh.ch.HttpError(w, "limit must be a positive integer", http.StatusBadRequest)
 			return
 		}
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		_, err = w.Write(data)
-		if err != nil {
-			log.Printf("error: %v", err)
-			rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
-			return
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "list"))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	end(nil)
+
+	docs := rh.dh.db
+	if now := time.Now(); len(rh.dh.exp) > 0 || rh.dh.owned || q.Has("filter") || q.Has("after") || limit > 0 {
+		ids := make([]string, 0, len(rh.dh.db))
+		for i, doc := range rh.dh.db {
+			if i > q.Get("after") && !rh.dh.expired(i, now) && rh.canAccess(r, doc) && filter.Match(doc) {
+				ids = append(ids, i)
+			}
 		}
+		if limit > 0 && len(ids) > limit {
+			sort.Strings(ids)
+			ids = ids[:limit]
+			next := *r.URL
+			nq := next.Query()
+			nq.Set("after", ids[limit-1])
+			next.RawQuery = nq.Encode()
+			w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+		}
+		docs = make(map[string]map[string]interface{}, len(ids))
+		for _, i := range ids {
+			docs[i] = rh.dh.db[i]
+		}
+	}
 
-		log.Printf("Resources Returned: %v\n", len(rh.dh.db))
+	if len(docs) > 0 {
+		rh.encode(w, r, docs, http.StatusOK)
+		rh.ch.logf(r, "Resources Returned: %v\n", len(docs))
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-	log.Printf("Resources Returned: %v\n", len(rh.dh.db))
+	rh.ch.logf(r, "Resources Returned: %v\n", len(docs))
 	return
 }
 
 // GetResourceHandler GET /api/resources/{id}
 func (rh *ResourceHandler) GetResourceHandler(w http.ResponseWriter, r *http.Request) {
-	rh.dh.mu.Lock()
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	i := mux.Vars(r)["id"]
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "get"), attribute.String("resource.id", i))
+	rh.dh.lock()
 	defer rh.dh.mu.Unlock()
 
-	i := mux.Vars(r)["id"]
-	err := CheckID(i, rh.dh.db)
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+	q := r.URL.Query()
+	if q.Has("version") || q.Has("asOf") {
+		end(nil)
+		rh.getRevision(w, r, i, q.Get("version"), q.Get("asOf"))
 		return
 	}
 
-	data, err := rh.ch.Marshal(rh.dh.db[i])
+	err := rh.checkID(i)
+	end(err)
 	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	_, err = w.Write(data)
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+	if !rh.checkAccess(w, r, rh.dh.db[i]) {
 		return
 	}
 
-	log.Printf("Resource Returned: %v\n", len(rh.dh.db[i]))
+	rh.applyTTL(w, i, 0, false)
+	rh.encode(w, r, rh.dh.db[i], http.StatusOK)
+	rh.ch.logf(r, "Resource Returned: %v\n", len(rh.dh.db[i]))
 	return
 
 }
 
 // CreateResourceHandler POST /api/resources/
 func (rh *ResourceHandler) CreateResourceHandler(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	rh.dh.mu.Lock()
-	defer rh.dh.mu.Unlock()
-
-	b, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+	if rh = rh.scope(w, r); rh == nil {
 		return
 	}
+	defer r.Body.Close()
 
-	obj := make(map[string]interface{})
-
-	err = rh.ch.Unmarshal(b, &obj)
+	obj, ttl, set, code, err := rh.decode(r)
 	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
 		return
 	}
 
-	i := uuid.New().String()
-	rh.dh.db[i] = obj
+	i := rh.idPolicy().NewID()
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "create"), attribute.String("resource.id", i))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
 
-	data, err := rh.ch.Marshal(rh.dh.db[i])
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+	if !rh.checkQuota(w, r) {
+		end(nil)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-
-	_, err = w.Write(data)
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
-		return
+	if !set && rh.dh.ttl > 0 {
+		ttl, set = rh.dh.ttl, true
 	}
+	rh.own(r, obj, nil)
+	rh.dh.db[i] = obj
+	rh.publish(EventCreated, i, rh.dh.record(i, obj, false), obj)
+	rh.applyTTL(w, i, ttl, set)
+	end(nil)
 
-	log.Printf("Resource Created: Id: %v\n", i)
+	w.Header().Set("Location", path.Join(r.URL.Path, i))
+	rh.encode(w, r, rh.dh.db[i], http.StatusCreated)
+	rh.ch.logf(r, "Resource Created: Id: %v\n", i)
 	return
 }
 
 // UpdateResourceHandler PUT /api/resources/{id}
 func (rh *ResourceHandler) UpdateResourceHandler(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	rh.dh.mu.Lock()
-	defer rh.dh.mu.Unlock()
-
-	i := mux.Vars(r)["id"]
-	err := CheckID(i, rh.dh.db)
-	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+	if rh = rh.scope(w, r); rh == nil {
 		return
 	}
+	defer r.Body.Close()
 
-	b, err := ioutil.ReadAll(r.Body)
+	obj, ttl, set, code, err := rh.decode(r)
 	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
 		return
 	}
 
-	obj := make(map[string]interface{})
+	i := mux.Vars(r)["id"]
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "update"), attribute.String("resource.id", i))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
 
-	err = rh.ch.Unmarshal(b, &obj)
+	err = rh.checkID(i)
 	if err != nil {
-		log.Printf("error: %v", err)
+		end(err)
+		rh.ch.logf(r, "error: %v", err)
 		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !rh.checkAccess(w, r, rh.dh.db[i]) {
+		end(nil)
+		return
+	}
 
+	rh.own(r, obj, rh.dh.db[i])
 	rh.dh.db[i] = obj
+	rh.publish(EventUpdated, i, rh.dh.record(i, obj, false), obj)
+	rh.applyTTL(w, i, ttl, set)
+	end(nil)
+
+	rh.encode(w, r, rh.dh.db[i], http.StatusAccepted)
+	rh.ch.logf(r, "Map Resource Updated: %v\n", i)
+	return
+}
+
+// PatchResourceHandler PATCH /api/resources/{id}
+// Applies the body as a JSON merge patch (RFC 7396): null removes a field, objects are merged recursively.
+func (rh *ResourceHandler) PatchResourceHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	defer r.Body.Close()
 
-	data, err := rh.ch.Marshal(rh.dh.db[i])
+	patch, ttl, set, code, err := rh.decode(r)
 	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
+	i := mux.Vars(r)["id"]
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "patch"), attribute.String("resource.id", i))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
 
-	_, err = w.Write(data)
+	err = rh.checkID(i)
 	if err != nil {
-		log.Printf("error: %v", err)
-		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		end(err)
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !rh.checkAccess(w, r, rh.dh.db[i]) {
+		end(nil)
 		return
 	}
 
-	log.Printf("Map Resource Updated: %v\n", i)
-	return
+	obj := mergePatch(rh.dh.db[i], patch)
+	rh.own(r, obj, rh.dh.db[i])
+	rh.dh.db[i] = obj
+	rh.publish(EventUpdated, i, rh.dh.record(i, obj, false), obj)
+	rh.applyTTL(w, i, ttl, set)
+	end(nil)
+
+	rh.encode(w, r, rh.dh.db[i], http.StatusOK)
+	rh.ch.logf(r, "Map Resource Patched: %v\n", i)
 }
 
 // DeleteResourceHandler DELETE /api/resources/{id}
 func (rh *ResourceHandler) DeleteResourceHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
 	defer r.Body.Close()
-	rh.dh.mu.Lock()
-	defer rh.dh.mu.Unlock()
 
 	i := mux.Vars(r)["id"]
-	err := CheckID(i, rh.dh.db)
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "delete"), attribute.String("resource.id", i))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	err := rh.checkID(i)
 	if err != nil {
-		log.Printf("error: %v", err)
+		end(err)
+		rh.ch.logf(r, "error: %v", err)
 		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !rh.checkAccess(w, r, rh.dh.db[i]) {
+		end(nil)
+		return
+	}
+
+	old := rh.dh.db[i]
+	rh.publish(EventDeleted, i, rh.dh.remove(i), old)
+	end(nil)
 
-	delete(rh.dh.db, i)
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", rh.ch.contentType())
 	w.WriteHeader(http.StatusNoContent)
 
-	log.Printf("Map Resource Deleted: %v\n", i)
+	rh.ch.logf(r, "Map Resource Deleted: %v\n", i)
 	return
 }
+
+// decode Reads and unmarshals the request document, extracting any TTL it carries.
+// The returned status code is meaningful only when err is not nil.
+func (rh *ResourceHandler) decode(r *http.Request) (obj map[string]interface{}, ttl time.Duration, set bool, code int, err error) {
+	end := rh.phase(r, "resource.decode")
+	defer func() { end(err) }()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, 0, false, http.StatusRequestEntityTooLarge, err
+		}
+		return nil, 0, false, http.StatusInternalServerError, err
+	}
+
+	obj = make(map[string]interface{})
+
+	err = rh.ch.Unmarshal(b, &obj)
+	if err != nil {
+		return nil, 0, false, http.StatusBadRequest, err
+	}
+
+	rh.dh.observePayload(len(b))
+	ttl, set, err = ttlFrom(r, obj)
+	if err != nil {
+		return nil, 0, false, http.StatusBadRequest, err
+	}
+	return obj, ttl, set, 0, nil
+}
+
+// encode Marshals v and writes it with the given status code.
+func (rh *ResourceHandler) encode(w http.ResponseWriter, r *http.Request, v interface{}, code int) {
+	var err error
+	end := rh.phase(r, "resource.encode")
+	defer func() { end(err) }()
+
+	data, err := rh.ch.Marshal(v)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.WriteHeader(code)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"github.com/angarcia/gorest/mw"
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
@@ -451,3 +452,117 @@ func TestResourceHandler_UpdateResourceHandler(t *testing.T) {
 		})
 	}
 }
+
+// TestCommonHandler_HttpError Error bodies carry the request id set by mw.RequestID.
+func TestCommonHandler_HttpError(t *testing.T) {
+	rh := CreateHandler(map[string]map[string]interface{}{})
+	h := mw.RequestID(http.HandlerFunc(rh.GetResourceHandler))
+
+	r, err := http.NewRequest("GET", "", nil)
+	if err != nil {
+		t.Fail()
+	}
+	r = mux.SetURLVars(r, map[string]string{"id": "dummy"})
+	r.Header.Set(mw.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	var e ErrorHttp
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != 400 || e.RequestID != "req-42" {
+		t.Errorf("got %+v want status 400 and request id req-42", e)
+	}
+}
+
+// TestResourceHandler_PatchResourceHandler PATCH /api/resources/{id}
+func TestResourceHandler_PatchResourceHandler(t *testing.T) {
+	id := "0bf8651a-0923-47b8-aed3-e9fc1505e497"
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+		doc  string
+	}{
+		{name: "PatchResource - Success", id: id, body: `{"lastname":"Kent","address":{"city":null,"zip":"10001"}}`, want: 200,
+			doc: `{"address":{"street":"1007 Mountain Dr","zip":"10001"},"lastname":"Kent","name":"Bruce"}`},
+		{name: "PatchResource - Remove Field Success", id: id, body: `{"address":null}`, want: 200, doc: `{"lastname":"Wayne","name":"Bruce"}`},
+		{name: "PatchResource - Bad JSON Failure", id: id, body: `{`, want: 400},
+		{name: "PatchResource - Not Exist Failure", id: "dummy", body: `{}`, want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := CreateHandler(map[string]map[string]interface{}{id: {"name": "Bruce", "lastname": "Wayne",
+				"address": map[string]interface{}{"street": "1007 Mountain Dr", "city": "Gotham"}}})
+			original := rh.dh.db[id]
+			r, _ := http.NewRequest("PATCH", "", strings.NewReader(tt.body))
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			rh.PatchResourceHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if tt.doc != "" && strings.TrimSpace(w.Body.String()) != tt.doc {
+				t.Errorf("got %s want %s", w.Body.String(), tt.doc)
+			}
+			if original["lastname"] != "Wayne" {
+				t.Errorf("stored document modified in place: %v", original)
+			}
+		})
+	}
+}
+
+// TestResourceHandler_GetResourcesHandler_Pages GET /api/resources?filter=&limit=&after=
+func TestResourceHandler_GetResourcesHandler_Pages(t *testing.T) {
+	db := map[string]map[string]interface{}{}
+	for _, i := range []string{"a", "b", "c", "d"} {
+		db[i] = map[string]interface{}{"group": "odd"}
+	}
+	db["b"]["group"], db["d"]["group"] = "even", "even"
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		ids   string
+		next  string
+	}{
+		{name: "GetResources - Filter Success", query: `filter=group+%3D%3D+"even"`, want: 200, ids: "b,d"},
+		{name: "GetResources - First Page Success", query: "limit=3", want: 200, ids: "a,b,c", next: "/api/resources?after=c&limit=3"},
+		{name: "GetResources - Last Page Success", query: "limit=3&after=c", want: 200, ids: "d"},
+		{name: "GetResources - Past End Success", query: "after=d", want: 204},
+		{name: "GetResources - Bad Filter Failure", query: "filter=group+%3D%3D", want: 400},
+		{name: "GetResources - Bad Limit Failure", query: "limit=0", want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := CreateHandler(db)
+			r, _ := http.NewRequest("GET", "/api/resources?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			rh.GetResourcesHandler(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("got %d want %d", w.Code, tt.want)
+			}
+			if w.Header().Get("Link") != "" != (tt.next != "") || tt.next != "" && !strings.Contains(w.Header().Get("Link"), "<"+tt.next+">") {
+				t.Errorf("got Link %q want next %q", w.Header().Get("Link"), tt.next)
+			}
+			if tt.ids == "" {
+				return
+			}
+			docs := map[string]interface{}{}
+			json.Unmarshal(w.Body.Bytes(), &docs)
+			for _, i := range strings.Split(tt.ids, ",") {
+				if _, ok := docs[i]; !ok {
+					t.Errorf("got %v missing %s", docs, i)
+				}
+			}
+			if len(docs) != len(strings.Split(tt.ids, ",")) {
+				t.Errorf("got %v want %s", docs, tt.ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import "time"
+
+// StartSweeper Runs sweep every interval in the background until the returned stop function is called.
+func (rh *ResourceHandler) StartSweeper(interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case now := <-t.C:
+				rh.sweep(now)
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+	}
+}
+
+// sweep Reaps expired resources, purges trash past its retention and drops idle rate limit buckets at now, in
+// every tenant store.
+func (rh *ResourceHandler) sweep(now time.Time) {
+	for _, h := range rh.all() {
+		h.dh.lock()
+		h.expire(now)
+		h.purgeTrash(now)
+		h.dh.dropIdleBuckets(now)
+		h.dh.mu.Unlock()
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/gorilla/mux"
+)
+
+// TenantQuota Limits on the number of tenants and on each tenant store. Zero fields are unlimited.
+type TenantQuota struct {
+	MaxTenants   int
+	MaxResources int
+}
+
+// Tenant Summary of a tenant store returned by the admin endpoints.
+type Tenant struct {
+	Name      string    `json:"name"`
+	Resources int       `json:"resources"`
+	Trash     int       `json:"trash"`
+	Created   time.Time `json:"created"`
+}
+
+// tenantStore A tenant's isolated store.
+type tenantStore struct {
+	dh      *DBHelper
+	created time.Time
+}
+
+// tenants Registry of tenant stores shared by the root handler and its tenant-scoped copies.
+type tenants struct {
+	mu        sync.Mutex
+	stores    map[string]*tenantStore
+	quota     TenantQuota
+	adminRole string
+	// deleted is called with the name of every deleted tenant.
+	deleted []func(name string)
+}
+
+// EnableTenancy Gives every tenant selected by mw.Tenant its own store, created through CreateTenantHandler with
+// the configuration of rh and limited by quota. Requests without a tenant keep using rh's store. The tenant
+// administration endpoints are restricted to principals holding adminRole.
+// It must be called after the rest of rh's configuration and before rh starts serving requests.
+func (rh *ResourceHandler) EnableTenancy(quota TenantQuota, adminRole string) {
+	rh.tenants = &tenants{stores: make(map[string]*tenantStore), quota: quota, adminRole: adminRole}
+}
+
+// OnTenantDeleted Calls f with the name of every tenant deleted through DeleteTenantHandler, e.g.
+// WebhookDispatcher.DropTenant, so state kept outside the tenant's store goes with it.
+// It must be called after EnableTenancy and before rh starts serving requests.
+func (rh *ResourceHandler) OnTenantDeleted(f func(name string)) {
+	rh.tenants.deleted = append(rh.tenants.deleted, f)
+}
+
+// scope Returns the handler serving the tenant of r. Reads of a tenant that does not exist are served from an
+// empty, unregistered store; other requests are answered 404 and scope returns nil.
+func (rh *ResourceHandler) scope(w http.ResponseWriter, r *http.Request) *ResourceHandler {
+	name := mw.TenantFrom(r.Context())
+	if rh.tenants == nil || rh.tenant != "" || name == "" {
+		return rh
+	}
+
+	ts := rh.tenants
+	ts.mu.Lock()
+	t, ok := ts.stores[name]
+	ts.mu.Unlock()
+	if ok {
+		return rh.with(name, t.dh)
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rh.ch.logf(r, "error: tenant %v not found", name)
+		rh.ch.HttpError(w, "the tenant provided does not exist", http.StatusNotFound)
+		return nil
+	}
+	return rh.with(name, rh.dh.child(ts.quota))
+}
+
+// with Returns a copy of rh bound to the store of tenant name.
+func (rh *ResourceHandler) with(name string, dh *DBHelper) *ResourceHandler {
+	return &ResourceHandler{ch: rh.ch, dh: dh, eb: rh.eb, tr: rh.tr, ids: rh.ids, tenant: name, tenants: rh.tenants}
+}
+
+// all Returns rh followed by a handler for every tenant store.
+func (rh *ResourceHandler) all() []*ResourceHandler {
+	hs := []*ResourceHandler{rh}
+	if rh.tenants == nil {
+		return hs
+	}
+	rh.tenants.mu.Lock()
+	defer rh.tenants.mu.Unlock()
+	for name, t := range rh.tenants.stores {
+		hs = append(hs, rh.with(name, t.dh))
+	}
+	return hs
+}
+
+// child Returns an empty store sharing dh's configuration and instrumentation.
+func (dh *DBHelper) child(quota TenantQuota) *DBHelper {
+	return &DBHelper{
+		db:           make(map[string]map[string]interface{}),
+		histSize:     dh.histSize,
+		soft:         dh.soft,
+		retention:    dh.retention,
+		ttl:          dh.ttl,
+		owned:        dh.owned,
+		adminRole:    dh.adminRole,
+		maxResources: quota.MaxResources,
+		lockWait:     dh.lockWait,
+		payload:      dh.payload,
+	}
+}
+
+// checkQuota Replies 403 when the store cannot take another resource. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) checkQuota(w http.ResponseWriter, r *http.Request) bool {
+	if rh.dh.maxResources <= 0 || len(rh.dh.db) < rh.dh.maxResources {
+		return true
+	}
+	rh.ch.logf(r, "error: tenant %v reached its quota of %v resources", rh.tenant, rh.dh.maxResources)
+	rh.ch.HttpError(w, fmt.Sprintf("tenant quota of %d resources exceeded", rh.dh.maxResources), http.StatusForbidden)
+	return false
+}
+
+// checkAdmin Replies 404 without tenancy, 401 for anonymous requests and 403 for requests scoped to a tenant or
+// made by a principal lacking the admin role, unless r may administer tenants.
+func (rh *ResourceHandler) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if rh.tenants == nil {
+		rh.ch.HttpError(w, "tenancy is not enabled", http.StatusNotFound)
+		return false
+	}
+	if name := mw.TenantFrom(r.Context()); name != "" {
+		rh.ch.logf(r, "error: tenant administration requested by tenant %v", name)
+		rh.ch.HttpError(w, "tenant administration is not available to tenant-scoped requests", http.StatusForbidden)
+		return false
+	}
+	p := mw.PrincipalFrom(r.Context())
+	if p == nil {
+		rh.ch.HttpError(w, "authentication required", http.StatusUnauthorized)
+		return false
+	}
+	if rh.tenants.adminRole == "" || !p.HasRole(rh.tenants.adminRole) {
+		rh.ch.logf(r, "error: tenant administration denied to %v", p.Subject)
+		rh.ch.HttpError(w, fmt.Sprintf("%s may not administer tenants", p.Subject), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// GetTenantsHandler GET /api/tenants
+func (rh *ResourceHandler) GetTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	if !rh.checkAdmin(w, r) {
+		return
+	}
+	stores := map[string]*tenantStore{}
+	rh.tenants.mu.Lock()
+	for name, t := range rh.tenants.stores {
+		stores[name] = t
+	}
+	rh.tenants.mu.Unlock()
+
+	list := make([]Tenant, 0, len(stores))
+	for name, t := range stores {
+		t.dh.lock()
+		list = append(list, Tenant{Name: name, Resources: len(t.dh.db), Trash: len(t.dh.trash), Created: t.created})
+		t.dh.mu.Unlock()
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+
+	rh.encode(w, r, list, http.StatusOK)
+	rh.ch.logf(r, "Tenants Returned: %v\n", len(list))
+}
+
+// CreateTenantHandler POST /api/tenants
+// Creates the empty store of the tenant named in the body, e.g. {"name":"acme"}.
+func (rh *ResourceHandler) CreateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if !rh.checkAdmin(w, r) {
+		return
+	}
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
+		return
+	}
+	var t Tenant
+	if err := rh.ch.Unmarshal(b, &t); err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !mw.ValidTenant(t.Name) {
+		rh.ch.HttpError(w, "the tenant name must be a lowercase DNS label", http.StatusBadRequest)
+		return
+	}
+
+	ts := rh.tenants
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, ok := ts.stores[t.Name]; ok {
+		rh.ch.logf(r, "error: tenant %v already exists", t.Name)
+		rh.ch.HttpError(w, "the tenant provided already exists", http.StatusConflict)
+		return
+	}
+	if ts.quota.MaxTenants > 0 && len(ts.stores) >= ts.quota.MaxTenants {
+		rh.ch.logf(r, "error: reached the limit of %v tenants", ts.quota.MaxTenants)
+		rh.ch.HttpError(w, fmt.Sprintf("limit of %d tenants exceeded", ts.quota.MaxTenants), http.StatusForbidden)
+		return
+	}
+	t = Tenant{Name: t.Name, Created: time.Now().UTC()}
+	ts.stores[t.Name] = &tenantStore{dh: rh.dh.child(ts.quota), created: t.Created}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, t.Name))
+	rh.encode(w, r, t, http.StatusCreated)
+	rh.ch.logf(r, "Tenant Created: %v\n", t.Name)
+}
+
+// DeleteTenantHandler DELETE /api/tenants/{name}
+// Drops the tenant's store with its trash and history, and whatever was registered with OnTenantDeleted. Its name
+// can then be created again, empty.
+func (rh *ResourceHandler) DeleteTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if !rh.checkAdmin(w, r) {
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	rh.tenants.mu.Lock()
+	_, ok := rh.tenants.stores[name]
+	delete(rh.tenants.stores, name)
+	rh.tenants.mu.Unlock()
+
+	if !ok {
+		rh.ch.logf(r, "error: tenant %v not found", name)
+		rh.ch.HttpError(w, "the tenant provided does not exist", http.StatusNotFound)
+		return
+	}
+	for _, f := range rh.tenants.deleted {
+		f(name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	rh.ch.logf(r, "Tenant Deleted: %v\n", name)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/gorilla/mux"
+)
+
+// asTenant Returns r scoped to tenant, or r unchanged for the default namespace.
+func asTenant(r *http.Request, tenant string) *http.Request {
+	if tenant == "" {
+		return r
+	}
+	return r.WithContext(mw.WithTenant(r.Context(), tenant))
+}
+
+// createTenant Creates tenant name through the admin endpoint and returns the status code.
+func createTenant(rh *ResourceHandler, name string) int {
+	r, _ := http.NewRequest("POST", "/api/tenants", strings.NewReader(`{"name":"`+name+`"}`))
+	w := httptest.NewRecorder()
+	rh.CreateTenantHandler(w, asPrincipal(r, "root", []string{"admin"}, nil))
+	return w.Code
+}
+
+// TestResourceHandler_Tenancy Tenant stores are isolated from each other and from the default store.
+func TestResourceHandler_Tenancy(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableTenancy(TenantQuota{MaxResources: 1}, "admin")
+	createTenant(rh, "acme")
+	createTenant(rh, "wayne")
+
+	create := func(tenant, body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		rh.CreateResourceHandler(w, asTenant(r, tenant))
+		return w
+	}
+	list := func(tenant string) map[string]map[string]interface{} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		rh.GetResourcesHandler(w, asTenant(r, tenant))
+		docs := map[string]map[string]interface{}{}
+		json.Unmarshal(w.Body.Bytes(), &docs)
+		return docs
+	}
+
+	tests := []struct {
+		name   string
+		tenant string
+		body   string
+		want   int
+	}{
+		{name: "CreateResource - Default Success", body: `{"name":"Default"}`, want: 201},
+		{name: "CreateResource - Default Unlimited Success", body: `{"name":"Default 2"}`, want: 201},
+		{name: "CreateResource - Tenant A Success", tenant: "acme", body: `{"name":"Acme"}`, want: 201},
+		{name: "CreateResource - Tenant B Success", tenant: "wayne", body: `{"name":"Wayne"}`, want: 201},
+		{name: "CreateResource - Tenant Quota Failure", tenant: "acme", body: `{"name":"Acme 2"}`, want: 403},
+		{name: "CreateResource - Unknown Tenant Failure", tenant: "ghost", body: `{"name":"Ghost"}`, want: 404},
+		{name: "CreateResource - Unknown Tenant Invalid Failure", tenant: "ghost", body: `not json`, want: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := create(tt.tenant, tt.body); w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	if n := len(list("")); n != 2 {
+		t.Errorf("default store: got %d resources want 2", n)
+	}
+	for _, tenant := range []string{"acme", "wayne"} {
+		docs := list(tenant)
+		if len(docs) != 1 {
+			t.Errorf("tenant %s: got %v want 1 resource", tenant, docs)
+		}
+		for i := range docs {
+			r, _ := http.NewRequest("GET", "/", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": i})
+			w := httptest.NewRecorder()
+			rh.GetResourceHandler(w, r)
+			if w.Code != 400 {
+				t.Errorf("tenant %s resource read from default store: got %d want %d", tenant, w.Code, 400)
+			}
+		}
+	}
+
+	// Reads and failed writes do not create tenants.
+	list("ghost")
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	rh.GetTenantsHandler(w, asPrincipal(r, "root", []string{"admin"}, nil))
+	var tenants []Tenant
+	json.Unmarshal(w.Body.Bytes(), &tenants)
+	if len(tenants) != 2 || tenants[0].Name != "acme" || tenants[0].Resources != 1 || tenants[1].Name != "wayne" {
+		t.Errorf("got tenants %+v want acme and wayne", tenants)
+	}
+}
+
+// TestResourceHandler_DeleteTenantHandler DELETE /api/tenants/{name}
+func TestResourceHandler_DeleteTenantHandler(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableTenancy(TenantQuota{}, "admin")
+	createTenant(rh, "acme")
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name":"Acme"}`))
+	rh.CreateResourceHandler(httptest.NewRecorder(), asTenant(r, "acme"))
+
+	tests := []struct {
+		name    string
+		tenant  string
+		subject string
+		roles   []string
+		scope   string
+		want    int
+	}{
+		{name: "DeleteTenant - Anonymous Failure", tenant: "acme", want: 401},
+		{name: "DeleteTenant - Not Admin Failure", tenant: "acme", subject: "bruce", roles: []string{"writer"}, want: 403},
+		{name: "DeleteTenant - Tenant Scoped Failure", tenant: "acme", subject: "root", roles: []string{"admin"}, scope: "wayne", want: 403},
+		{name: "DeleteTenant - Success", tenant: "acme", subject: "root", roles: []string{"admin"}, want: 204},
+		{name: "DeleteTenant - Already Deleted Failure", tenant: "acme", subject: "root", roles: []string{"admin"}, want: 404},
+		{name: "DeleteTenant - Not Exist Failure", tenant: "ghost", subject: "root", roles: []string{"admin"}, want: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("DELETE", "/", nil)
+			r = mux.SetURLVars(r, map[string]string{"name": tt.tenant})
+			w := httptest.NewRecorder()
+
+			rh.DeleteTenantHandler(w, asTenant(asPrincipal(r, tt.subject, tt.roles, nil), tt.scope))
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestResourceHandler_DeleteTenantHandler_Webhooks Deleting a tenant removes its webhooks, waiting retries and
+// dead letters, leaving other tenants' alone.
+func TestResourceHandler_DeleteTenantHandler_Webhooks(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableTenancy(TenantQuota{}, "admin")
+	wd := CreateWebhookDispatcher(rh.Events(), 1)
+	defer wd.Close(context.Background())
+	rh.OnTenantDeleted(wd.DropTenant)
+	for _, tenant := range []string{"acme", "wayne"} {
+		createTenant(rh, tenant)
+		wd.hooks[tenant] = &Webhook{ID: tenant, URL: "http://example.com", Tenant: tenant}
+		wd.logs[tenant] = []Delivery{{ID: tenant, Webhook: tenant, Tenant: tenant}}
+		wd.dead = append(wd.dead, Delivery{ID: tenant, Webhook: tenant, Tenant: tenant})
+		wd.retries[&Delivery{ID: tenant, Webhook: tenant, Tenant: tenant}] = time.AfterFunc(time.Hour, func() {})
+	}
+
+	r, _ := http.NewRequest("DELETE", "/", nil)
+	r = mux.SetURLVars(r, map[string]string{"name": "acme"})
+	w := httptest.NewRecorder()
+	rh.DeleteTenantHandler(w, asPrincipal(r, "root", []string{"admin"}, nil))
+	if w.Code != 204 {
+		t.Fatalf("got %d want %d", w.Code, 204)
+	}
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if _, ok := wd.hooks["acme"]; ok || len(wd.hooks) != 1 {
+		t.Errorf("got webhooks %v want wayne's only", wd.hooks)
+	}
+	if _, ok := wd.logs["acme"]; ok {
+		t.Errorf("got acme delivery log %v want none", wd.logs["acme"])
+	}
+	if len(wd.dead) != 1 || wd.dead[0].Tenant != "wayne" {
+		t.Errorf("got dead letters %+v want wayne's only", wd.dead)
+	}
+	if len(wd.retries) != 1 {
+		t.Errorf("got %d retries want %d", len(wd.retries), 1)
+	}
+	for d := range wd.retries {
+		if d.Tenant != "wayne" {
+			t.Errorf("got retry of %v want wayne's only", d.Tenant)
+		}
+	}
+}
+
+// TestResourceHandler_CreateTenantHandler POST /api/tenants
+func TestResourceHandler_CreateTenantHandler(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableTenancy(TenantQuota{MaxTenants: 2}, "admin")
+
+	tests := []struct {
+		name    string
+		body    string
+		subject string
+		roles   []string
+		scope   string
+		want    int
+	}{
+		{name: "CreateTenant - Success", body: `{"name":"acme"}`, subject: "root", roles: []string{"admin"}, want: 201},
+		{name: "CreateTenant - Anonymous Failure", body: `{"name":"wayne"}`, want: 401},
+		{name: "CreateTenant - Not Admin Failure", body: `{"name":"wayne"}`, subject: "bruce", roles: []string{"writer"}, want: 403},
+		{name: "CreateTenant - Tenant Scoped Failure", body: `{"name":"wayne"}`, subject: "root", roles: []string{"admin"}, scope: "acme", want: 403},
+		{name: "CreateTenant - Invalid Body Failure", body: `not json`, subject: "root", roles: []string{"admin"}, want: 400},
+		{name: "CreateTenant - Invalid Name Failure", body: `{"name":"Wayne Corp"}`, subject: "root", roles: []string{"admin"}, want: 400},
+		{name: "CreateTenant - Already Exists Failure", body: `{"name":"acme"}`, subject: "root", roles: []string{"admin"}, want: 409},
+		{name: "CreateTenant - Second Success", body: `{"name":"wayne"}`, subject: "root", roles: []string{"admin"}, want: 201},
+		{name: "CreateTenant - Limit Failure", body: `{"name":"stark"}`, subject: "root", roles: []string{"admin"}, want: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/api/tenants", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			rh.CreateTenantHandler(w, asTenant(asPrincipal(r, tt.subject, tt.roles, nil), tt.scope))
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestResourceHandler_GetTenantsHandler GET /api/tenants
+func TestResourceHandler_GetTenantsHandler(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableTenancy(TenantQuota{}, "admin")
+
+	tests := []struct {
+		name    string
+		subject string
+		roles   []string
+		scope   string
+		want    int
+	}{
+		{name: "GetTenants - Success", subject: "root", roles: []string{"admin"}, want: 200},
+		{name: "GetTenants - Anonymous Failure", want: 401},
+		{name: "GetTenants - Not Admin Failure", subject: "bruce", roles: []string{"writer"}, want: 403},
+		{name: "GetTenants - Tenant Scoped Failure", subject: "root", roles: []string{"admin"}, scope: "acme", want: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+
+			rh.GetTenantsHandler(w, asTenant(asPrincipal(r, tt.subject, tt.roles, nil), tt.scope))
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
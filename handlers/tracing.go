@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName Instrumentation name used for spans started by this package.
+const TracerName = "github.com/angarcia/gorest/handlers"
+
+// Trace Makes rh start decode, store and encode spans under each request span using tp.
+// It must be called before rh starts serving requests.
+func (rh *ResourceHandler) Trace(tp trace.TracerProvider) {
+	rh.tr = tp.Tracer(TracerName)
+}
+
+// phase Starts a child span of the request span and returns the function ending it, which marks the span
+// as failed when given a non-nil error. Without a tracer it does nothing.
+func (rh *ResourceHandler) phase(r *http.Request, name string, attrs ...attribute.KeyValue) func(error) {
+	if rh.tr == nil {
+		return func(error) {}
+	}
+	_, span := rh.tr.Start(r.Context(), name, trace.WithAttributes(attrs...))
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
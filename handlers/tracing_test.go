@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestResourceHandler_Trace Decode, store and encode spans are children of the request span.
+func TestResourceHandler_Trace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	rh := CreateHandler(map[string]map[string]interface{}{})
+	rh.Trace(tp)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	r, _ := http.NewRequestWithContext(ctx, "POST", "", strings.NewReader(`{"name":"Bruce","lastname":"Wayne"}`))
+	rh.CreateResourceHandler(httptest.NewRecorder(), r)
+	parent.End()
+
+	var names []string
+	for _, s := range sr.Ended() {
+		if s.Name() == "request" {
+			continue
+		}
+		names = append(names, s.Name())
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the request span", s.Name())
+		}
+	}
+	if strings.Join(names, ",") != "resource.decode,resource.store,resource.encode" {
+		t.Errorf("got spans %v", names)
+	}
+}
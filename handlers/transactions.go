@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Operation kinds of a Transaction.
+const (
+	OpCreate  = "create"
+	OpReplace = "replace"
+	OpPatch   = "patch"
+	OpDelete  = "delete"
+	OpRead    = "read"
+)
+
+// opMethods The method of the single request equivalent to each operation, which the access policy must allow
+// on the resources collection.
+var opMethods = map[string]string{
+	OpCreate:  http.MethodPost,
+	OpReplace: http.MethodPut,
+	OpPatch:   http.MethodPatch,
+	OpDelete:  http.MethodDelete,
+	OpRead:    http.MethodGet,
+}
+
+// maxOperations Most operations accepted in one transaction, bounding how long it holds the store.
+const maxOperations = 100
+
+// Operation A step of a Transaction. Except for create, it names an existing resource by ID; IfVersion and If
+// are preconditions on that resource as left by the preceding operations.
+type Operation struct {
+	Op string `json:"op"`
+	ID string `json:"id,omitempty"`
+	// Document is the created or replacing document, or the JSON merge patch. It may carry a TTL in _ttl.
+	Document map[string]interface{} `json:"document,omitempty"`
+	// IfVersion requires the resource to be at this version, as listed by its history.
+	IfVersion int `json:"ifVersion,omitempty"`
+	// If requires the resource to match this filter expression, e.g. "balance >= 50".
+	If string `json:"if,omitempty"`
+}
+
+// Transaction Operations applied in order, atomically: either all of them take effect or none does.
+type Transaction struct {
+	Operations []Operation `json:"operations"`
+}
+
+// OpResult The outcome of an Operation, with the status code of the equivalent single request.
+type OpResult struct {
+	Status   int                    `json:"status"`
+	ID       string                 `json:"id"`
+	Version  int                    `json:"version"`
+	Document map[string]interface{} `json:"document,omitempty"`
+}
+
+// TransactionResult The results of a committed Transaction, one per operation.
+type TransactionResult struct {
+	Results []OpResult `json:"results"`
+}
+
+// txState A resource as left by the operations staged so far; doc is nil once deleted.
+type txState struct {
+	doc     map[string]interface{}
+	version int
+}
+
+// txWrite A staged change, applied to the store on commit.
+type txWrite struct {
+	op      string
+	id      string
+	doc     map[string]interface{}
+	ttl     time.Duration
+	set     bool
+	created bool
+}
+
+// txn A transaction being staged on the store of rh, which it holds locked.
+type txn struct {
+	rh     *ResourceHandler
+	r      *http.Request
+	view   map[string]txState
+	writes []txWrite
+	live   int
+}
+
+// ExecuteTransactionHandler POST /api/transactions
+// Stages the operations against the locked store, so no other request sees or interleaves with them, and applies
+// them only when all succeed. The first failing operation is reported with its index and the transaction is
+// rolled back.
+func (rh *ResourceHandler) ExecuteTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), code)
+		return
+	}
+	var tx Transaction
+	if err := rh.ch.Unmarshal(b, &tx); err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(tx.Operations) == 0 || len(tx.Operations) > maxOperations {
+		rh.ch.HttpError(w, fmt.Sprintf("a transaction takes 1 to %d operations", maxOperations), http.StatusBadRequest)
+		return
+	}
+
+	// The route is authorized as the transactions collection; every operation must also be allowed on resources,
+	// so a transaction cannot do what the equivalent single requests may not.
+	for n, op := range tx.Operations {
+		method, ok := opMethods[op.Op]
+		if !ok {
+			rh.ch.HttpError(w, fmt.Sprintf("operation %d (%s): unknown operation, want one of %s, %s, %s, %s or %s", n, op.Op, OpCreate, OpReplace, OpPatch, OpDelete, OpRead), http.StatusBadRequest)
+			return
+		}
+		if code, err := mw.Authorize(r, "resources", method); err != nil {
+			rh.ch.logf(r, "error: transaction denied: operation %d (%s): %v", n, op.Op, err)
+			rh.ch.HttpError(w, fmt.Sprintf("operation %d (%s): %v", n, op.Op, err), code)
+			return
+		}
+	}
+
+	end := rh.phase(r, "resource.store", attribute.String("db.operation", "transaction"), attribute.Int("transaction.operations", len(tx.Operations)))
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	rh.dh.observePayload(len(b))
+
+	t := &txn{rh: rh, r: r, view: make(map[string]txState), live: len(rh.dh.db)}
+	res := TransactionResult{Results: make([]OpResult, 0, len(tx.Operations))}
+	for n, op := range tx.Operations {
+		result, code, err := t.stage(op)
+		if err != nil {
+			end(err)
+			rh.ch.logf(r, "error: transaction rolled back: operation %d (%s): %v", n, op.Op, err)
+			rh.ch.HttpError(w, fmt.Sprintf("operation %d (%s): %v", n, op.Op, err), code)
+			return
+		}
+		res.Results = append(res.Results, result)
+	}
+	t.commit()
+	end(nil)
+
+	rh.encode(w, r, res, http.StatusOK)
+	rh.ch.logf(r, "Transaction Committed: Operations: %v Writes: %v\n", len(tx.Operations), len(t.writes))
+}
+
+// get Returns resource i as left by the staged operations.
+func (t *txn) get(i string) (txState, bool) {
+	if s, ok := t.view[i]; ok {
+		return s, s.doc != nil
+	}
+	doc, ok := t.rh.dh.db[i]
+	if ok && t.rh.dh.expired(i, time.Now()) {
+		return txState{}, false
+	}
+	return txState{doc: doc, version: t.rh.dh.version(i)}, ok
+}
+
+// stage Checks op, of a kind listed in opMethods, against the staged state and records its change.
+// The returned status code is meaningful only when err is not nil.
+func (t *txn) stage(op Operation) (OpResult, int, error) {
+	if op.Op == OpCreate {
+		return t.create(op)
+	}
+
+	if err := t.rh.idPolicy().Validate(op.ID); err != nil {
+		return OpResult{}, http.StatusBadRequest, err
+	}
+	cur, ok := t.get(op.ID)
+	if !ok {
+		return OpResult{}, http.StatusBadRequest, errors.New("the id provided does not exist in database")
+	}
+	if !t.rh.canAccess(t.r, cur.doc) {
+		return OpResult{}, http.StatusForbidden, errors.New("the resource belongs to another principal")
+	}
+	if op.IfVersion != 0 && op.IfVersion != cur.version {
+		return OpResult{}, http.StatusPreconditionFailed, fmt.Errorf("resource is at version %d, not %d", cur.version, op.IfVersion)
+	}
+	if op.If != "" {
+		f, err := ParseFilter(op.If)
+		if err != nil {
+			return OpResult{}, http.StatusBadRequest, err
+		}
+		if !f.Match(cur.doc) {
+			return OpResult{}, http.StatusPreconditionFailed, fmt.Errorf("resource does not match %q", op.If)
+		}
+	}
+
+	switch op.Op {
+	case OpRead:
+		return OpResult{Status: http.StatusOK, ID: op.ID, Version: cur.version, Document: cur.doc}, 0, nil
+	case OpDelete:
+		t.view[op.ID] = txState{version: cur.version + 1}
+		t.writes = append(t.writes, txWrite{op: OpDelete, id: op.ID})
+		t.live--
+		return OpResult{Status: http.StatusNoContent, ID: op.ID, Version: cur.version + 1}, 0, nil
+	}
+
+	obj := op.Document
+	if obj == nil {
+		obj = make(map[string]interface{})
+	}
+	ttl, set, err := bodyTTL(obj)
+	if err != nil {
+		return OpResult{}, http.StatusBadRequest, err
+	}
+	status := http.StatusAccepted
+	if op.Op == OpPatch {
+		obj, status = mergePatch(cur.doc, obj), http.StatusOK
+	}
+	t.rh.own(t.r, obj, cur.doc)
+	t.view[op.ID] = txState{doc: obj, version: cur.version + 1}
+	t.writes = append(t.writes, txWrite{op: op.Op, id: op.ID, doc: obj, ttl: ttl, set: set})
+	return OpResult{Status: status, ID: op.ID, Version: cur.version + 1, Document: obj}, 0, nil
+}
+
+// create Stages a new resource with an id from the id policy.
+// The returned status code is meaningful only when err is not nil.
+func (t *txn) create(op Operation) (OpResult, int, error) {
+	if op.ID != "" || op.IfVersion != 0 || op.If != "" {
+		return OpResult{}, http.StatusBadRequest, errors.New("create takes no id or preconditions")
+	}
+	if limit := t.rh.dh.maxResources; limit > 0 && t.live >= limit {
+		return OpResult{}, http.StatusForbidden, fmt.Errorf("tenant quota of %d resources exceeded", limit)
+	}
+	obj := op.Document
+	if obj == nil {
+		obj = make(map[string]interface{})
+	}
+	ttl, set, err := bodyTTL(obj)
+	if err != nil {
+		return OpResult{}, http.StatusBadRequest, err
+	}
+	if !set && t.rh.dh.ttl > 0 {
+		ttl, set = t.rh.dh.ttl, true
+	}
+
+	i := t.rh.idPolicy().NewID()
+	v := t.rh.dh.version(i) + 1
+	t.rh.own(t.r, obj, nil)
+	t.view[i] = txState{doc: obj, version: v}
+	t.writes = append(t.writes, txWrite{op: OpCreate, id: i, doc: obj, ttl: ttl, set: set, created: true})
+	t.live++
+	return OpResult{Status: http.StatusCreated, ID: i, Version: v, Document: obj}, 0, nil
+}
+
+// commit Applies the staged writes in order, recording and publishing each like the single requests do.
+func (t *txn) commit() {
+	dh := t.rh.dh
+	for _, wr := range t.writes {
+		if wr.op == OpDelete {
+			old := dh.db[wr.id]
+			t.rh.publish(EventDeleted, wr.id, dh.remove(wr.id), old)
+			continue
+		}
+
+		dh.db[wr.id] = wr.doc
+		typ := EventUpdated
+		if wr.created {
+			typ = EventCreated
+		}
+		t.rh.publish(typ, wr.id, dh.record(wr.id, wr.doc, false), wr.doc)
+		if wr.set {
+			dh.setTTL(wr.id, wr.ttl)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/gorilla/mux"
+)
+
+// TestResourceHandler_ExecuteTransactionHandler POST /api/transactions applies all operations or none.
+func TestResourceHandler_ExecuteTransactionHandler(t *testing.T) {
+	a, b := "0bf8651a-0923-47b8-aed3-e9fc1505e497", "5b1c1cb4-4f2c-4c6e-9a59-5d0b7c4d8b21"
+	rh := CreateHandler(map[string]map[string]interface{}{
+		a: {"name": "Bruce", "balance": 100.0},
+		b: {"name": "Clark", "balance": 0.0},
+	})
+	rh.dh.record(a, rh.dh.db[a], false)
+	rh.dh.record(b, rh.dh.db[b], false)
+
+	tests := []struct {
+		name     string
+		body     string
+		want     int
+		statuses []int
+		events   uint64
+		balances [2]float64
+		count    int
+	}{
+		{name: "Transfer - Success", body: `{"operations":[
+			{"op":"patch","id":"` + a + `","ifVersion":1,"if":"balance >= 50","document":{"balance":50}},
+			{"op":"patch","id":"` + b + `","document":{"balance":50}},
+			{"op":"read","id":"` + a + `"}]}`,
+			want: 200, statuses: []int{200, 200, 200}, events: 2, balances: [2]float64{50, 50}, count: 2},
+		{name: "Transfer - Stale Version Failure", body: `{"operations":[
+			{"op":"patch","id":"` + a + `","ifVersion":1,"document":{"balance":0}}]}`,
+			want: 412, balances: [2]float64{50, 50}, count: 2},
+		{name: "Transfer - Insufficient Balance Failure", body: `{"operations":[
+			{"op":"patch","id":"` + b + `","document":{"balance":100}},
+			{"op":"patch","id":"` + a + `","if":"balance >= 100","document":{"balance":-50}}]}`,
+			want: 412, balances: [2]float64{50, 50}, count: 2},
+		{name: "Rollback - Read Deleted Failure", body: `{"operations":[
+			{"op":"create","document":{"name":"Diana","balance":10}},
+			{"op":"delete","id":"` + a + `"},
+			{"op":"read","id":"` + a + `"}]}`,
+			want: 400, balances: [2]float64{50, 50}, count: 2},
+		{name: "Create Replace Delete - Success", body: `{"operations":[
+			{"op":"create","document":{"name":"Diana","balance":10}},
+			{"op":"replace","id":"` + b + `","ifVersion":2,"document":{"name":"Clark Kent","balance":60}},
+			{"op":"delete","id":"` + a + `"}]}`,
+			want: 200, statuses: []int{201, 202, 204}, events: 3, balances: [2]float64{-1, 60}, count: 2},
+		{name: "Unknown Operation - Failure", body: `{"operations":[{"op":"merge","id":"` + b + `"}]}`, want: 400, balances: [2]float64{-1, 60}, count: 2},
+		{name: "Invalid Filter - Failure", body: `{"operations":[{"op":"read","id":"` + b + `","if":"balance"}]}`, want: 400, balances: [2]float64{-1, 60}, count: 2},
+		{name: "Create With ID - Failure", body: `{"operations":[{"op":"create","id":"` + b + `"}]}`, want: 400, balances: [2]float64{-1, 60}, count: 2},
+		{name: "Empty - Failure", body: `{"operations":[]}`, want: 400, balances: [2]float64{-1, 60}, count: 2},
+		{name: "Invalid Body - Failure", body: `{"operations":`, want: 400, balances: [2]float64{-1, 60}, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := rh.eb.Last()
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			rh.ExecuteTransactionHandler(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("got %d want %d: %s", w.Code, tt.want, w.Body)
+			}
+
+			if tt.want == 200 {
+				var res TransactionResult
+				json.Unmarshal(w.Body.Bytes(), &res)
+				if len(res.Results) != len(tt.statuses) {
+					t.Fatalf("got %s want %d results", w.Body, len(tt.statuses))
+				}
+				for n, s := range tt.statuses {
+					if res.Results[n].Status != s {
+						t.Errorf("operation %d: got %d want %d", n, res.Results[n].Status, s)
+					}
+				}
+			}
+			if got := rh.eb.Last() - last; got != tt.events {
+				t.Errorf("got %d events want %d", got, tt.events)
+			}
+
+			if len(rh.dh.db) != tt.count {
+				t.Errorf("got %d resources want %d", len(rh.dh.db), tt.count)
+			}
+			for n, i := range []string{a, b} {
+				doc, ok := rh.dh.db[i]
+				switch {
+				case tt.balances[n] < 0 && ok:
+					t.Errorf("resource %d: got %v want deleted", n, doc)
+				case tt.balances[n] >= 0 && (!ok || doc["balance"] != tt.balances[n]):
+					t.Errorf("resource %d: got %v want balance %v", n, doc, tt.balances[n])
+				}
+			}
+		})
+	}
+
+	if v := rh.dh.version(b); v != 3 {
+		t.Errorf("got version %d want %d", v, 3)
+	}
+}
+
+// TestResourceHandler_ExecuteTransactionHandler_Policy Every operation needs the access the equivalent single
+// request needs on resources.
+func TestResourceHandler_ExecuteTransactionHandler_Policy(t *testing.T) {
+	a := "0bf8651a-0923-47b8-aed3-e9fc1505e497"
+	rh := CreateHandler(map[string]map[string]interface{}{a: {"name": "Bruce"}})
+	policy := &mw.Policy{Rules: []mw.PolicyRule{
+		{Roles: []string{"writer"}, Collections: []string{"resources", "transactions"}, Methods: []string{"GET", "POST", "PUT", "PATCH"}},
+		{Roles: []string{"admin"}, Collections: []string{"*"}, Methods: []string{"*"}},
+	}}
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/").Subrouter()
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := &mw.Principal{Subject: "tester", Roles: []string{r.Header.Get("Role")}}
+			next.ServeHTTP(w, r.WithContext(mw.WithPrincipal(r.Context(), p)))
+		})
+	})
+	api.Use(mw.Authorization(policy, rh.ch.HttpError))
+	rh.Routes(api)
+
+	tests := []struct {
+		name string
+		role string
+		body string
+		want int
+	}{
+		{name: "Transaction Create - Success", role: "writer", body: `{"operations":[{"op":"create","document":{"name":"Clark"}}]}`, want: 200},
+		{name: "Transaction Delete - Policy Failure", role: "writer", body: `{"operations":[{"op":"create","document":{"name":"Diana"}},{"op":"delete","id":"` + a + `"}]}`, want: 403},
+		{name: "Transaction Delete - Admin Success", role: "admin", body: `{"operations":[{"op":"delete","id":"` + a + `"}]}`, want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/api/transactions", strings.NewReader(tt.body))
+			r.Header.Set("Role", tt.role)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+	if len(rh.dh.db) != 1 {
+		t.Errorf("got %d resources want 1: the denied transaction must not create", len(rh.dh.db))
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Trashed A soft-deleted resource awaiting restore or purge.
+type Trashed struct {
+	Document map[string]interface{} `json:"document"`
+	Deleted  time.Time              `json:"deleted"`
+	PurgeAt  *time.Time             `json:"purgeAt,omitempty"`
+}
+
+// EnableSoftDelete Makes DELETE move resources to the trash instead of dropping them.
+// Trashed resources are purged by the sweeper once retention has elapsed; zero keeps them until purged explicitly.
+func (rh *ResourceHandler) EnableSoftDelete(retention time.Duration) {
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+	rh.dh.soft = true
+	rh.dh.retention = retention
+}
+
+// purgeTrash Purges trashed resources whose retention has elapsed at now. Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) purgeTrash(now time.Time) {
+	if rh.dh.retention <= 0 {
+		return
+	}
+	for i, t := range rh.dh.trash {
+		if now.Sub(t.Deleted) >= rh.dh.retention {
+			rh.dh.purge(i)
+			rh.ch.printf("Trash Resource Purged: %v\n", i)
+		}
+	}
+}
+
+// moveToTrash Moves resource i from the store to the trash. Caller must hold rh.dh.mu.
+func (dh *DBHelper) moveToTrash(i string) {
+	if dh.trash == nil {
+		dh.trash = make(map[string]Trashed)
+	}
+	t := Trashed{Document: dh.db[i], Deleted: time.Now().UTC()}
+	if dh.retention > 0 {
+		at := t.Deleted.Add(dh.retention)
+		t.PurgeAt = &at
+	}
+	dh.trash[i] = t
+	delete(dh.db, i)
+}
+
+// purge Drops trashed resource i together with its revision history. Caller must hold rh.dh.mu.
+func (dh *DBHelper) purge(i string) {
+	delete(dh.trash, i)
+	delete(dh.hist, i)
+}
+
+// checkTrash Validates i and replies 400/403/404 unless it names a trashed resource the caller may access.
+// Caller must hold rh.dh.mu.
+func (rh *ResourceHandler) checkTrash(w http.ResponseWriter, r *http.Request, i string) bool {
+	if err := rh.idPolicy().Validate(i); err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, ok := rh.dh.trash[i]; !ok {
+		rh.ch.logf(r, "error: %v not in trash", i)
+		rh.ch.HttpError(w, "the id provided does not exist in trash", http.StatusNotFound)
+		return false
+	}
+	return rh.checkAccess(w, r, rh.dh.trash[i].Document)
+}
+
+// GetTrashHandler GET /api/trash
+func (rh *ResourceHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	trash := make(map[string]Trashed, len(rh.dh.trash))
+	for i, t := range rh.dh.trash {
+		if rh.canAccess(r, t.Document) {
+			trash[i] = t
+		}
+	}
+	data, err := rh.ch.Marshal(trash)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+
+	rh.ch.logf(r, "Trash Returned: %v\n", len(trash))
+}
+
+// RestoreTrashHandler POST /api/trash/{id}/restore
+// The restored resource gets the default TTL like a created one, replacing any expiry left from before.
+func (rh *ResourceHandler) RestoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	defer r.Body.Close()
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	i := mux.Vars(r)["id"]
+	if !rh.checkTrash(w, r, i) || !rh.checkQuota(w, r) {
+		return
+	}
+
+	obj := rh.dh.trash[i].Document
+	delete(rh.dh.trash, i)
+	rh.dh.db[i] = obj
+	rh.publish(EventCreated, i, rh.dh.record(i, obj, false), obj)
+	rh.applyTTL(w, i, rh.dh.ttl, true)
+
+	data, err := rh.ch.Marshal(obj)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		rh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", rh.ch.contentType())
+	w.WriteHeader(http.StatusCreated)
+
+	_, err = w.Write(data)
+	if err != nil {
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+
+	rh.ch.logf(r, "Trash Resource Restored: %v\n", i)
+}
+
+// PurgeTrashHandler DELETE /api/trash/{id}
+func (rh *ResourceHandler) PurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	defer r.Body.Close()
+	rh.dh.lock()
+	defer rh.dh.mu.Unlock()
+
+	i := mux.Vars(r)["id"]
+	if !rh.checkTrash(w, r, i) {
+		return
+	}
+
+	rh.dh.purge(i)
+	w.WriteHeader(http.StatusNoContent)
+
+	rh.ch.logf(r, "Trash Resource Purged: %v\n", i)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// trashHandler Returns a soft-deleting handler with one resource already deleted through DELETE.
+func trashHandler(t *testing.T, retention time.Duration) *ResourceHandler {
+	rh := &ResourceHandler{
+		ch: &CommonHandler{},
+		dh: &DBHelper{
+			db: map[string]map[string]interface{}{"0bf8651a-0923-47b8-aed3-e9fc1505e497": {"name": "Clark", "lastname": "Kent"}},
+			mu: sync.Mutex{},
+		},
+	}
+	rh.EnableSoftDelete(retention)
+
+	r, _ := http.NewRequest("DELETE", "", strings.NewReader(``))
+	r = mux.SetURLVars(r, map[string]string{"id": "0bf8651a-0923-47b8-aed3-e9fc1505e497"})
+	w := httptest.NewRecorder()
+	rh.DeleteResourceHandler(w, r)
+	if w.Code != 204 {
+		t.Fatalf("got %d want %d", w.Code, 204)
+	}
+	return rh
+}
+
+// TestResourceHandler_TrashHandlers GET /api/trash, POST /api/trash/{id}/restore, DELETE /api/trash/{id}
+func TestResourceHandler_TrashHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(rh *ResourceHandler) http.HandlerFunc
+		id      string
+		want    int
+		inDB    bool
+		inTrash bool
+	}{
+		{name: "GetTrash - Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.GetTrashHandler }, want: 200, inTrash: true},
+		{name: "RestoreTrash - Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreTrashHandler }, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 201, inDB: true},
+		{name: "RestoreTrash - Invalid UUID Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreTrashHandler }, id: "dummy", want: 400, inTrash: true},
+		{name: "RestoreTrash - UUID Not Exist Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.RestoreTrashHandler }, id: "0bf8651a-0923-47b8-aed3-e9fc1505e496", want: 404, inTrash: true},
+		{name: "PurgeTrash - Success", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.PurgeTrashHandler }, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 204},
+		{name: "PurgeTrash - UUID Not Exist Failure", handler: func(rh *ResourceHandler) http.HandlerFunc { return rh.PurgeTrashHandler }, id: "0bf8651a-0923-47b8-aed3-e9fc1505e496", want: 404, inTrash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := trashHandler(t, 0)
+
+			r, _ := http.NewRequest("POST", "", strings.NewReader(``))
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			tt.handler(rh)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if _, ok := rh.dh.db["0bf8651a-0923-47b8-aed3-e9fc1505e497"]; ok != tt.inDB {
+				t.Errorf("in db: got %v want %v", ok, tt.inDB)
+			}
+			if _, ok := rh.dh.trash["0bf8651a-0923-47b8-aed3-e9fc1505e497"]; ok != tt.inTrash {
+				t.Errorf("in trash: got %v want %v", ok, tt.inTrash)
+			}
+		})
+	}
+}
+
+// TestResourceHandler_sweep Trashed resources are purged once retention has elapsed.
+func TestResourceHandler_sweep(t *testing.T) {
+	rh := trashHandler(t, time.Hour)
+
+	rh.sweep(time.Now().Add(time.Minute))
+	if len(rh.dh.trash) != 1 {
+		t.Errorf("purged before retention elapsed")
+	}
+	rh.sweep(time.Now().Add(2 * time.Hour))
+	if len(rh.dh.trash) != 0 || len(rh.dh.hist) != 0 {
+		t.Errorf("got trash %v history %v want both empty", rh.dh.trash, rh.dh.hist)
+	}
+}
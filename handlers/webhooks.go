@@ -0,0 +1,579 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Webhook A subscription delivering matching resource events to URL.
+type Webhook struct {
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Events  []string  `json:"events,omitempty"`
+	Filter  string    `json:"filter,omitempty"`
+	Secret  string    `json:"secret,omitempty"`
+	Tenant  string    `json:"tenant,omitempty"`
+	Created time.Time `json:"created"`
+	filter  *Filter
+	// principal registered the webhook, nil when anonymous; it only receives documents it may access.
+	principal *mw.Principal
+}
+
+// matches Reports whether e should be delivered to wh, whose principal may access documents according to access.
+func (wh *Webhook) matches(e Event, access func(p *mw.Principal, doc map[string]interface{}) bool) bool {
+	if e.Tenant != wh.Tenant {
+		return false
+	}
+	if access != nil && !access(wh.principal, e.Document) {
+		return false
+	}
+	if len(wh.Events) > 0 {
+		found := false
+		for _, t := range wh.Events {
+			found = found || t == e.Type
+		}
+		if !found {
+			return false
+		}
+	}
+	return wh.filter.Match(e.Document)
+}
+
+// Delivery A single event addressed to a webhook and the outcome of its latest attempt.
+type Delivery struct {
+	ID       string    `json:"id"`
+	Webhook  string    `json:"webhook"`
+	Seq      uint64    `json:"seq"`
+	Event    string    `json:"event"`
+	Resource string    `json:"resource"`
+	Tenant   string    `json:"tenant,omitempty"`
+	Attempt  int       `json:"attempt"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+	body     []byte
+	secret   string
+	url      string
+}
+
+// WebhookDispatcher Queues and sends signed webhook deliveries with exponential backoff.
+type WebhookDispatcher struct {
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Access, when set, restricts each webhook to events on documents its registering principal may access,
+	// e.g. ResourceHandler.CanAccess.
+	Access func(p *mw.Principal, doc map[string]interface{}) bool
+	// AllowPrivate lets webhooks target loopback, link-local and private addresses. They are refused by default,
+	// at registration and when Client dials, so webhooks cannot reach services behind the server.
+	AllowPrivate bool
+
+	mu       sync.Mutex
+	hooks    map[string]*Webhook
+	logs     map[string][]Delivery
+	dead     []Delivery
+	logSize  int
+	deadSize int
+	queue    chan *Delivery
+	// retries holds the timers of deliveries waiting to be retried.
+	retries map[*Delivery]*time.Timer
+	closed  bool
+	// quit makes workers drain the queue and return; stop makes them return at once.
+	quit chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// CreateWebhookDispatcher creation/initialization of the dispatcher and its workers, fed from eb.
+func CreateWebhookDispatcher(eb *EventBroker, workers int) *WebhookDispatcher {
+	wd := &WebhookDispatcher{
+		MaxAttempts: 6,
+		Backoff:     time.Second,
+		MaxBackoff:  5 * time.Minute,
+		hooks:       make(map[string]*Webhook),
+		logs:        make(map[string][]Delivery),
+		logSize:     100,
+		deadSize:    1000,
+		queue:       make(chan *Delivery, 1024),
+		retries:     make(map[*Delivery]*time.Timer),
+		quit:        make(chan struct{}),
+		stop:        make(chan struct{}),
+	}
+	// Connections are made directly, never through a proxy, so the dialed address is the one checked.
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: wd.control}).DialContext
+	wd.Client = &http.Client{Timeout: 10 * time.Second, Transport: tr}
+	for n := 0; n < workers; n++ {
+		wd.wg.Add(1)
+		go wd.work()
+	}
+	if eb != nil {
+		eb.Attach(wd.Enqueue)
+	}
+	return wd
+}
+
+// Close Stops the dispatcher. Queued deliveries are still attempted, once, until ctx ends; those left over, failed
+// ones and those waiting for a retry are dead-lettered instead of being dropped. Later calls do nothing.
+func (wd *WebhookDispatcher) Close(ctx context.Context) {
+	wd.mu.Lock()
+	if wd.closed {
+		wd.mu.Unlock()
+		return
+	}
+	wd.closed = true
+	for d, t := range wd.retries {
+		t.Stop()
+		delete(wd.retries, d)
+		wd.bury(*d)
+	}
+	wd.mu.Unlock()
+	close(wd.quit)
+
+	done := make(chan struct{})
+	go func() {
+		wd.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		close(wd.stop)
+	}
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	for {
+		select {
+		case d := <-wd.queue:
+			if _, ok := wd.hooks[d.Webhook]; !ok {
+				continue
+			}
+			d.Error, d.Time = "dispatcher closed", time.Now().UTC()
+			wd.bury(*d)
+		default:
+			return
+		}
+	}
+}
+
+// DropTenant Removes the webhooks of tenant with their delivery logs, waiting retries and dead letters, so a
+// deleted tenant's subscriptions stop delivering. Queued deliveries of the removed webhooks are skipped.
+func (wd *WebhookDispatcher) DropTenant(tenant string) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	for id, hook := range wd.hooks {
+		if hook.Tenant == tenant {
+			delete(wd.hooks, id)
+			delete(wd.logs, id)
+		}
+	}
+	for d, t := range wd.retries {
+		if d.Tenant == tenant {
+			t.Stop()
+			delete(wd.retries, d)
+		}
+	}
+	dead := wd.dead[:0]
+	for _, d := range wd.dead {
+		if d.Tenant != tenant {
+			dead = append(dead, d)
+		}
+	}
+	wd.dead = dead
+}
+
+// Enqueue Creates a delivery of e for every matching webhook. It never blocks the caller.
+func (wd *WebhookDispatcher) Enqueue(e Event) {
+	body, err := json.Marshal(struct {
+		Seq uint64 `json:"seq"`
+		Event
+	}{e.Seq, e})
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	for _, wh := range wd.hooks {
+		if !wh.matches(e, wd.Access) {
+			continue
+		}
+		d := &Delivery{
+			ID:       uuid.New().String(),
+			Webhook:  wh.ID,
+			Seq:      e.Seq,
+			Event:    e.Type,
+			Resource: e.ID,
+			Tenant:   e.Tenant,
+			body:     body,
+			secret:   wh.Secret,
+			url:      wh.URL,
+		}
+		wd.push(d)
+	}
+}
+
+// push Hands d to the workers, dead-lettering it if the queue is full or the dispatcher closed. Caller must hold mu.
+func (wd *WebhookDispatcher) push(d *Delivery) {
+	if wd.closed {
+		d.Error, d.Time = "dispatcher closed", time.Now().UTC()
+		wd.bury(*d)
+		return
+	}
+	select {
+	case wd.queue <- d:
+	default:
+		d.Error = "delivery queue full"
+		d.Time = time.Now().UTC()
+		wd.bury(*d)
+	}
+}
+
+func (wd *WebhookDispatcher) work() {
+	defer wd.wg.Done()
+	for {
+		select {
+		case <-wd.stop:
+			return
+		case d := <-wd.queue:
+			wd.attempt(d)
+		case <-wd.quit:
+			// Drain what is queued, then stop.
+			for {
+				select {
+				case <-wd.stop:
+					return
+				case d := <-wd.queue:
+					wd.attempt(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Sign Returns the value of the X-Gorest-Signature header for body under secret.
+func Sign(secret string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// attempt Sends d once and records the outcome, scheduling a retry or dead-lettering on failure. Deliveries of
+// webhooks removed since they were queued are dropped.
+func (wd *WebhookDispatcher) attempt(d *Delivery) {
+	wd.mu.Lock()
+	_, ok := wd.hooks[d.Webhook]
+	wd.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	d.Attempt++
+	d.Status, d.Error = 0, ""
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gorest-Event", d.Event)
+		req.Header.Set("X-Gorest-Delivery", d.ID)
+		if d.secret != "" {
+			req.Header.Set("X-Gorest-Signature", Sign(d.secret, d.body))
+		}
+		var res *http.Response
+		if res, err = wd.Client.Do(req); err == nil {
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			d.Status = res.StatusCode
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				err = fmt.Errorf("receiver responded %d", res.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.Time = time.Now().UTC()
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	l := append(wd.logs[d.Webhook], *d)
+	if len(l) > wd.logSize {
+		l = l[len(l)-wd.logSize:]
+	}
+	wd.logs[d.Webhook] = l
+
+	if err == nil {
+		log.Printf("Webhook Delivered: %v Attempt: %v\n", d.ID, d.Attempt)
+		return
+	}
+	log.Printf("error: webhook delivery %v attempt %v: %v", d.ID, d.Attempt, err)
+
+	if _, ok := wd.hooks[d.Webhook]; !ok {
+		return
+	}
+	if d.Attempt >= wd.MaxAttempts || wd.closed {
+		wd.bury(*d)
+		return
+	}
+
+	wait := wd.Backoff << (d.Attempt - 1)
+	if wait > wd.MaxBackoff || wait <= 0 {
+		wait = wd.MaxBackoff
+	}
+	wd.retries[d] = time.AfterFunc(wait, func() {
+		wd.mu.Lock()
+		defer wd.mu.Unlock()
+		// Close dead-letters the deliveries it finds waiting.
+		if _, ok := wd.retries[d]; !ok {
+			return
+		}
+		delete(wd.retries, d)
+		wd.push(d)
+	})
+}
+
+// bury Appends d to the bounded dead-letter list. Caller must hold mu.
+func (wd *WebhookDispatcher) bury(d Delivery) {
+	wd.dead = append(wd.dead, d)
+	if len(wd.dead) > wd.deadSize {
+		wd.dead = wd.dead[len(wd.dead)-wd.deadSize:]
+	}
+	log.Printf("Webhook Dead-Lettered: %v\n", d.ID)
+}
+
+// privateIP Reports whether ip is a loopback, link-local, private or unspecified address.
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// checkTarget Refuses webhook URLs naming a private address or localhost unless AllowPrivate is set. Host names
+// are checked again once resolved, when Client dials.
+func (wd *WebhookDispatcher) checkTarget(u string) error {
+	if wd.AllowPrivate {
+		return nil
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(p.Hostname())
+	if ip := net.ParseIP(host); host == "localhost" || strings.HasSuffix(host, ".localhost") || ip != nil && privateIP(ip) {
+		return errors.New("url must not target a loopback, link-local or private address")
+	}
+	return nil
+}
+
+// control net.Dialer hook refusing connections to private addresses unless AllowPrivate is set.
+func (wd *WebhookDispatcher) control(network, address string, _ syscall.RawConn) error {
+	if wd.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+		return fmt.Errorf("webhook delivery to private address %s refused", host)
+	}
+	return nil
+}
+
+// validate Checks a webhook registration and compiles its filter.
+func (wh *Webhook) validate() error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	for _, t := range wh.Events {
+		if t != EventCreated && t != EventUpdated && t != EventDeleted {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	wh.filter, err = ParseFilter(wh.Filter)
+	return err
+}
+
+// public Returns a copy of wh safe to render, without its secret.
+func (wh Webhook) public() Webhook {
+	wh.Secret = ""
+	return wh
+}
+
+// WebhookHandler contains webhook handler data
+type WebhookHandler struct {
+	ch *CommonHandler
+	wd *WebhookDispatcher
+}
+
+// CreateWebhookHandler creation/initialization of webhook handler.
+func CreateWebhookHandler(wd *WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		ch: &CommonHandler{Marshaler: nil, Unmarshaler: nil},
+		wd: wd,
+	}
+}
+
+// write Marshals v and writes it with the given status code.
+func (wh *WebhookHandler) write(w http.ResponseWriter, r *http.Request, v interface{}, code int) {
+	data, err := wh.ch.Marshal(v)
+	if err != nil {
+		wh.ch.logf(r, "error: %v", err)
+		wh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_, err = w.Write(data)
+	if err != nil {
+		wh.ch.logf(r, "error: %v", err)
+	}
+}
+
+// CreateWebhookHandler POST /api/webhooks
+func (wh *WebhookHandler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		wh.ch.logf(r, "error: %v", err)
+		wh.ch.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hook := &Webhook{}
+	err = wh.ch.Unmarshal(b, hook)
+	if err == nil {
+		err = hook.validate()
+	}
+	if err == nil {
+		err = wh.wd.checkTarget(hook.URL)
+	}
+	if err != nil {
+		wh.ch.logf(r, "error: %v", err)
+		wh.ch.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook.ID = uuid.New().String()
+	hook.Tenant = mw.TenantFrom(r.Context())
+	hook.principal = mw.PrincipalFrom(r.Context())
+	hook.Created = time.Now().UTC()
+
+	wh.wd.mu.Lock()
+	wh.wd.hooks[hook.ID] = hook
+	wh.wd.mu.Unlock()
+
+	wh.write(w, r, hook.public(), http.StatusCreated)
+	wh.ch.logf(r, "Webhook Created: Id: %v\n", hook.ID)
+}
+
+// GetWebhooksHandler GET /api/webhooks
+func (wh *WebhookHandler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	wh.wd.mu.Lock()
+	hooks := make([]Webhook, 0, len(wh.wd.hooks))
+	for _, hook := range wh.wd.hooks {
+		if hook.Tenant == mw.TenantFrom(r.Context()) {
+			hooks = append(hooks, hook.public())
+		}
+	}
+	wh.wd.mu.Unlock()
+
+	wh.write(w, r, hooks, http.StatusOK)
+	wh.ch.logf(r, "Webhooks Returned: %v\n", len(hooks))
+}
+
+// hook Looks up the webhook named in the route, replying 404 when absent.
+func (wh *WebhookHandler) hook(w http.ResponseWriter, r *http.Request) (Webhook, bool) {
+	i := mux.Vars(r)["id"]
+
+	wh.wd.mu.Lock()
+	hook, ok := wh.wd.hooks[i]
+	wh.wd.mu.Unlock()
+
+	if !ok || hook.Tenant != mw.TenantFrom(r.Context()) {
+		wh.ch.logf(r, "error: webhook %v not found", i)
+		wh.ch.HttpError(w, "the webhook id provided does not exist", http.StatusNotFound)
+		return Webhook{}, false
+	}
+	return hook.public(), true
+}
+
+// GetWebhookHandler GET /api/webhooks/{id}
+func (wh *WebhookHandler) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if hook, ok := wh.hook(w, r); ok {
+		wh.write(w, r, hook, http.StatusOK)
+	}
+}
+
+// DeleteWebhookHandler DELETE /api/webhooks/{id}
+func (wh *WebhookHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	hook, ok := wh.hook(w, r)
+	if !ok {
+		return
+	}
+
+	wh.wd.mu.Lock()
+	delete(wh.wd.hooks, hook.ID)
+	delete(wh.wd.logs, hook.ID)
+	wh.wd.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+	wh.ch.logf(r, "Webhook Deleted: %v\n", hook.ID)
+}
+
+// GetDeliveriesHandler GET /api/webhooks/{id}/deliveries
+func (wh *WebhookHandler) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	hook, ok := wh.hook(w, r)
+	if !ok {
+		return
+	}
+
+	wh.wd.mu.Lock()
+	l := append([]Delivery{}, wh.wd.logs[hook.ID]...)
+	wh.wd.mu.Unlock()
+
+	wh.write(w, r, l, http.StatusOK)
+}
+
+// GetDeadLettersHandler GET /api/webhooks/dead-letters
+func (wh *WebhookHandler) GetDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	wh.wd.mu.Lock()
+	l := make([]Delivery, 0, len(wh.wd.dead))
+	for _, d := range wh.wd.dead {
+		if d.Tenant == mw.TenantFrom(r.Context()) {
+			l = append(l, d)
+		}
+	}
+	wh.wd.mu.Unlock()
+
+	wh.write(w, r, l, http.StatusOK)
+}
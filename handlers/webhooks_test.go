@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/angarcia/gorest/mw"
+	"github.com/gorilla/mux"
+)
+
+// TestWebhookHandler_CreateWebhookHandler POST /api/webhooks
+func TestWebhookHandler_CreateWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		allowPrivate bool
+		want         int
+	}{
+		{name: "CreateWebhook - Success", body: `{"url":"http://example.com/hook","events":["created"],"secret":"s3cret"}`, want: 201},
+		{name: "CreateWebhook - Allowed Private Success", body: `{"url":"http://localhost/hook"}`, allowPrivate: true, want: 201},
+		{name: "CreateWebhook - Invalid URL Failure", body: `{"url":"example.com/hook"}`, want: 400},
+		{name: "CreateWebhook - Scheme Failure", body: `{"url":"file:///etc/passwd"}`, want: 400},
+		{name: "CreateWebhook - Localhost Failure", body: `{"url":"http://localhost/hook"}`, want: 400},
+		{name: "CreateWebhook - Loopback Failure", body: `{"url":"http://127.0.0.1:8181/api/resources"}`, want: 400},
+		{name: "CreateWebhook - Link-Local Failure", body: `{"url":"http://169.254.169.254/latest/meta-data"}`, want: 400},
+		{name: "CreateWebhook - Private Failure", body: `{"url":"https://10.0.0.8/hook"}`, want: 400},
+		{name: "CreateWebhook - Private IPv6 Failure", body: `{"url":"http://[::1]/hook"}`, want: 400},
+		{name: "CreateWebhook - Unknown Event Failure", body: `{"url":"http://example.com/hook","events":["dummy"]}`, want: 400},
+		{name: "CreateWebhook - Invalid Filter Failure", body: `{"url":"http://example.com/hook","filter":"name ="}`, want: 400},
+		{name: "CreateWebhook - Invalid Request Failure", body: `text`, want: 400},
+		{name: "CreateWebhook - Failed ioutil.ReadAll()", want: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wd := CreateWebhookDispatcher(nil, 0)
+			wd.AllowPrivate = tt.allowPrivate
+			wh := CreateWebhookHandler(wd)
+
+			var r *http.Request
+			if tt.body == "" {
+				r, _ = http.NewRequest("POST", "", errReader(0))
+			} else {
+				r, _ = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			}
+			w := httptest.NewRecorder()
+
+			wh.CreateWebhookHandler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if w.Code == 201 && strings.Contains(w.Body.String(), "s3cret") {
+				t.Errorf("secret returned in response %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestWebhookHandler_GetWebhookHandler GET/DELETE /api/webhooks/{id}
+func TestWebhookHandler_GetWebhookHandler(t *testing.T) {
+	wd := CreateWebhookDispatcher(nil, 0)
+	wd.hooks["0bf8651a-0923-47b8-aed3-e9fc1505e497"] = &Webhook{ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497", URL: "http://localhost/hook"}
+	wh := CreateWebhookHandler(wd)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		want    int
+	}{
+		{name: "GetWebhook - Success", handler: wh.GetWebhookHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200},
+		{name: "GetDeliveries - Success", handler: wh.GetDeliveriesHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200},
+		{name: "GetWebhook - Not Exist Failure", handler: wh.GetWebhookHandler, id: "dummy", want: 404},
+		{name: "DeleteWebhook - Success", handler: wh.DeleteWebhookHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 204},
+		{name: "DeleteWebhook - Not Exist Failure", handler: wh.DeleteWebhookHandler, id: "0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			tt.handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestWebhookDispatcher_Enqueue Signed delivery to a receiver, retries and dead-lettering.
+func TestWebhookDispatcher_Enqueue(t *testing.T) {
+	type received struct {
+		path string
+		sig  string
+		body []byte
+	}
+	var mu sync.Mutex
+	var got []received
+	fails := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, received{path: r.URL.Path, sig: r.Header.Get("X-Gorest-Signature"), body: b})
+
+		// The live receiver fails once and then recovers; the dead one never does.
+		if r.URL.Path == "/live" && fails == 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.URL.Path == "/live" {
+			fails--
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	eb := CreateEventBroker(16, 16)
+	wd := CreateWebhookDispatcher(eb, 2)
+	defer wd.Close(context.Background())
+	wd.AllowPrivate = true
+	wd.MaxAttempts = 3
+	wd.Backoff = time.Millisecond
+	for _, hook := range []*Webhook{
+		{ID: "live", URL: srv.URL + "/live", Events: []string{EventCreated}, Secret: "s3cret"},
+		{ID: "dead", URL: srv.URL + "/dead", Filter: `name == "Bruce"`},
+	} {
+		if err := hook.validate(); err != nil {
+			t.Fatal(err)
+		}
+		wd.hooks[hook.ID] = hook
+	}
+
+	eb.Publish(Event{Type: EventUpdated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e496", Document: map[string]interface{}{"name": "Clark"}})
+	eb.Publish(Event{Type: EventCreated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497", Document: map[string]interface{}{"name": "Bruce"}})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		wd.mu.Lock()
+		done := len(wd.dead) == 1 && len(wd.logs["live"]) == 2
+		wd.mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries did not settle: dead %v live %v", wd.dead, wd.logs["live"])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 5 {
+		t.Errorf("got %d requests want %d", len(got), 5)
+	}
+	for _, r := range got {
+		if r.path != "/live" {
+			continue
+		}
+		if r.sig != Sign("s3cret", r.body) {
+			t.Errorf("got signature %q want %q", r.sig, Sign("s3cret", r.body))
+		}
+		var e Event
+		if err := json.Unmarshal(r.body, &e); err != nil || e.ID != "0bf8651a-0923-47b8-aed3-e9fc1505e497" {
+			t.Errorf("got body %s", r.body)
+		}
+	}
+	if wd.dead[0].Webhook != "dead" || wd.dead[0].Attempt != 3 {
+		t.Errorf("got dead letter %+v", wd.dead[0])
+	}
+}
+
+// TestWebhook_matches With ownership, webhooks only receive events on documents their principal may access.
+func TestWebhook_matches(t *testing.T) {
+	rh := CreateHandler(make(map[string]map[string]interface{}))
+	rh.EnableOwnership("admin")
+	bruce := map[string]interface{}{"name": "Bruce", ownerField: "bruce"}
+	shared := map[string]interface{}{"name": "Clark", ownerField: "clark", groupsField: []interface{}{"league"}}
+
+	tests := []struct {
+		name      string
+		principal *mw.Principal
+		doc       map[string]interface{}
+		want      bool
+	}{
+		{name: "Matches - Owner Success", principal: &mw.Principal{Subject: "bruce"}, doc: bruce, want: true},
+		{name: "Matches - Group Success", principal: &mw.Principal{Subject: "diana", Groups: []string{"league"}}, doc: shared, want: true},
+		{name: "Matches - Admin Success", principal: &mw.Principal{Subject: "root", Roles: []string{"admin"}}, doc: bruce, want: true},
+		{name: "Matches - Unowned Success", doc: map[string]interface{}{"name": "Alfred"}, want: true},
+		{name: "Matches - Other Owner Failure", principal: &mw.Principal{Subject: "clark"}, doc: bruce, want: false},
+		{name: "Matches - Anonymous Failure", doc: bruce, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := &Webhook{URL: "http://example.com", principal: tt.principal}
+			if err := wh.validate(); err != nil {
+				t.Fatal(err)
+			}
+			e := Event{Type: EventCreated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497", Document: tt.doc}
+			if got := wh.matches(e, rh.CanAccess); got != tt.want {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWebhookDispatcher_Close Queued deliveries are drained until the deadline; the rest and waiting retries are
+// dead-lettered.
+func TestWebhookDispatcher_Close(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		block     bool
+		timeout   time.Duration
+		delivered int
+		dead      int
+		err       string
+	}{
+		{name: "Close - Drain Success", status: 200, timeout: 5 * time.Second, delivered: 3},
+		{name: "Close - Waiting Retries Dead-Lettered", status: 503, timeout: 5 * time.Second, dead: 3, err: "receiver responded 503"},
+		{name: "Close - Deadline Dead-Letters Queue", status: 200, block: true, timeout: 50 * time.Millisecond, dead: 2, err: "dispatcher closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			arrived := make(chan struct{}, 8)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case arrived <- struct{}{}:
+				default:
+				}
+				if tt.block {
+					<-release
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			defer close(release)
+
+			wd := CreateWebhookDispatcher(nil, 1)
+			wd.AllowPrivate = true
+			wd.Backoff = time.Hour
+			hook := &Webhook{ID: "hook", URL: srv.URL}
+			if err := hook.validate(); err != nil {
+				t.Fatal(err)
+			}
+			wd.hooks[hook.ID] = hook
+			for n := 0; n < 3; n++ {
+				wd.Enqueue(Event{Type: EventCreated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497", Document: map[string]interface{}{"name": "Bruce"}})
+			}
+			if tt.status != 200 {
+				// Let every delivery fail once and wait for its retry.
+				deadline := time.Now().Add(10 * time.Second)
+				for {
+					wd.mu.Lock()
+					waiting := len(wd.retries)
+					wd.mu.Unlock()
+					if waiting == 3 {
+						break
+					}
+					if time.Now().After(deadline) {
+						t.Fatalf("got %d deliveries waiting for a retry want %d", waiting, 3)
+					}
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+			if tt.block {
+				// Let the worker take the first delivery and hang on it.
+				select {
+				case <-arrived:
+				case <-time.After(10 * time.Second):
+					t.Fatalf("first delivery never reached the receiver")
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			wd.Close(ctx)
+			wd.Close(ctx)
+
+			wd.mu.Lock()
+			defer wd.mu.Unlock()
+			delivered := 0
+			for _, d := range wd.logs["hook"] {
+				if d.Error == "" {
+					delivered++
+				}
+			}
+			if delivered != tt.delivered {
+				t.Errorf("got %d delivered want %d", delivered, tt.delivered)
+			}
+			if len(wd.dead) != tt.dead {
+				t.Fatalf("got %d dead letters want %d: %+v", len(wd.dead), tt.dead, wd.dead)
+			}
+			for _, d := range wd.dead {
+				if d.Error != tt.err {
+					t.Errorf("got dead letter error %q want %q", d.Error, tt.err)
+				}
+			}
+		})
+	}
+}
+
+// TestWebhookDispatcher_control Deliveries to private addresses are refused when dialing unless allowed.
+func TestWebhookDispatcher_control(t *testing.T) {
+	var received int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer srv.Close()
+
+	wd := CreateWebhookDispatcher(nil, 1)
+	defer wd.Close(context.Background())
+	wd.MaxAttempts = 1
+	// Registered directly, as a host name resolving to a private address would pass checkTarget.
+	wd.hooks["hook"] = &Webhook{ID: "hook", URL: srv.URL}
+	wd.Enqueue(Event{Type: EventCreated, ID: "0bf8651a-0923-47b8-aed3-e9fc1505e497"})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		wd.mu.Lock()
+		dead := append([]Delivery{}, wd.dead...)
+		wd.mu.Unlock()
+		if len(dead) == 1 {
+			if !strings.Contains(dead[0].Error, "private address") {
+				t.Errorf("got error %q want the private address refused", dead[0].Error)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery was not dead-lettered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&received); n != 0 {
+		t.Errorf("got %d requests want %d", n, 0)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsRequest Client message to manage subscriptions on a connection.
+type wsRequest struct {
+	Action string   `json:"action"`
+	Sub    string   `json:"subscription"`
+	IDs    []string `json:"ids,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	Filter string   `json:"filter,omitempty"`
+}
+
+// wsResponse Server message: acknowledgements, errors and matching events.
+type wsResponse struct {
+	Type  string     `json:"type"`
+	Sub   string     `json:"subscription,omitempty"`
+	Seq   uint64     `json:"seq,omitempty"`
+	Event *Event     `json:"event,omitempty"`
+	Error *ErrorHttp `json:"error,omitempty"`
+}
+
+// wsSubscription Selection of events a client asked for. Empty ids/types match everything.
+type wsSubscription struct {
+	ids    map[string]bool
+	types  map[string]bool
+	filter *Filter
+}
+
+func (s *wsSubscription) match(e Event) bool {
+	if len(s.ids) > 0 && !s.ids[e.ID] {
+		return false
+	}
+	if len(s.types) > 0 && !s.types[e.Type] {
+		return false
+	}
+	return s.filter.Match(e.Document)
+}
+
+// wsConn One upgraded connection and its named subscriptions.
+type wsConn struct {
+	conn *websocket.Conn
+	wmu  sync.Mutex
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+
+	// allow Hides events of other tenants and on resources the connected principal may not access.
+	allow func(Event) bool
+}
+
+func (c *wsConn) send(m wsResponse) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(m)
+}
+
+func (c *wsConn) handle(req wsRequest) wsResponse {
+	if req.Sub == "" {
+		return wsResponse{Type: "error", Error: &ErrorHttp{Status: http.StatusBadRequest, Msg: "subscription name is required"}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch req.Action {
+	case "subscribe":
+		f, err := ParseFilter(req.Filter)
+		if err != nil {
+			return wsResponse{Type: "error", Sub: req.Sub, Error: &ErrorHttp{Status: http.StatusBadRequest, Msg: err.Error()}}
+		}
+		s := &wsSubscription{ids: map[string]bool{}, types: map[string]bool{}, filter: f}
+		for _, i := range req.IDs {
+			s.ids[i] = true
+		}
+		for _, t := range req.Types {
+			s.types[t] = true
+		}
+		c.subs[req.Sub] = s
+		return wsResponse{Type: "subscribed", Sub: req.Sub}
+	case "unsubscribe":
+		delete(c.subs, req.Sub)
+		return wsResponse{Type: "unsubscribed", Sub: req.Sub}
+	}
+	return wsResponse{Type: "error", Sub: req.Sub, Error: &ErrorHttp{Status: http.StatusBadRequest, Msg: "unknown action"}}
+}
+
+// deliver Sends e once per matching subscription.
+func (c *wsConn) deliver(e Event) error {
+	if c.allow != nil && !c.allow(e) {
+		return nil
+	}
+	c.mu.Lock()
+	var names []string
+	for name, s := range c.subs {
+		if s.match(e) {
+			names = append(names, name)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, name := range names {
+		if err := c.send(wsResponse{Type: "event", Sub: name, Seq: e.Seq, Event: &e}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWebSocketHandler GET /api/resources/ws
+func (rh *ResourceHandler) GetWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	if rh = rh.scope(w, r); rh == nil {
+		return
+	}
+	if rh.eb == nil {
+		rh.ch.HttpError(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		rh.ch.logf(r, "error: %v", err)
+		return
+	}
+	defer conn.Close()
+	c := &wsConn{conn: conn, subs: make(map[string]*wsSubscription)}
+	c.allow = func(e Event) bool { return rh.visible(r, e) }
+
+	// Only events published after the connection is established are delivered.
+	last := rh.eb.Last()
+	sub, _, _ := rh.eb.Subscribe(last)
+	defer func() { sub.Close() }()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					rh.ch.logf(r, "error: %v", err)
+				}
+				return
+			}
+			if err := c.send(c.handle(req)); err != nil {
+				rh.ch.logf(r, "error: %v", err)
+				return
+			}
+		}
+	}()
+	rh.ch.logf(r, "WebSocket Opened: %v\n", r.RemoteAddr)
+
+	for {
+		select {
+		case <-done:
+			rh.ch.logf(r, "WebSocket Closed: %v\n", r.RemoteAddr)
+			return
+		case e, ok := <-sub.C:
+			if !ok && rh.eb.Closed() {
+				msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+				rh.ch.logf(r, "WebSocket Closed: server shutting down\n")
+				return
+			}
+			if !ok {
+				// The broker dropped this consumer for lagging; resume from the replay buffer.
+				var replay []Event
+				var gap bool
+				sub, replay, gap = rh.eb.Subscribe(last)
+				if gap {
+					if err := c.send(wsResponse{Type: "reset"}); err != nil {
+						rh.ch.logf(r, "error: %v", err)
+						return
+					}
+				}
+				for _, e := range replay {
+					if err := c.deliver(e); err != nil {
+						rh.ch.logf(r, "error: %v", err)
+						return
+					}
+					last = e.Seq
+				}
+				continue
+			}
+			if err := c.deliver(e); err != nil {
+				rh.ch.logf(r, "error: %v", err)
+				return
+			}
+			last = e.Seq
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestResourceHandler_GetWebSocketHandler GET /api/resources/ws
+func TestResourceHandler_GetWebSocketHandler(t *testing.T) {
+	rh := ResourceHandler{
+		ch: &CommonHandler{},
+		dh: &DBHelper{
+			db: map[string]map[string]interface{}{},
+			mu: sync.Mutex{},
+		},
+		eb: CreateEventBroker(16, 16),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(rh.GetWebSocketHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	requests := []struct {
+		req  wsRequest
+		want string
+	}{
+		{req: wsRequest{Action: "subscribe", Sub: "bruce", Filter: `name == "Bruce"`}, want: "subscribed"},
+		{req: wsRequest{Action: "subscribe", Sub: "bad", Filter: `name =`}, want: "error"},
+		{req: wsRequest{Action: "subscribe", Sub: "gone", Types: []string{EventDeleted}}, want: "subscribed"},
+		{req: wsRequest{Action: "unsubscribe", Sub: "gone"}, want: "unsubscribed"},
+		{req: wsRequest{Action: "dummy", Sub: "bruce"}, want: "error"},
+	}
+	for _, tt := range requests {
+		if err := conn.WriteJSON(tt.req); err != nil {
+			t.Fatal(err)
+		}
+		var res wsResponse
+		if err := conn.ReadJSON(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Type != tt.want {
+			t.Errorf("got %s want %s", res.Type, tt.want)
+		}
+	}
+
+	rh.publish(EventCreated, "0bf8651a-0923-47b8-aed3-e9fc1505e496", 1, map[string]interface{}{"name": "Clark"})
+	rh.publish(EventDeleted, "0bf8651a-0923-47b8-aed3-e9fc1505e496", 2, map[string]interface{}{"name": "Clark"})
+	rh.publish(EventCreated, "0bf8651a-0923-47b8-aed3-e9fc1505e497", 1, map[string]interface{}{"name": "Bruce"})
+
+	var res wsResponse
+	if err := conn.ReadJSON(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != "event" || res.Sub != "bruce" || res.Event.ID != "0bf8651a-0923-47b8-aed3-e9fc1505e497" {
+		t.Errorf("got %+v want event for bruce", res)
+	}
+}
@@ -1,41 +1,253 @@
 package main
 
 import (
+	"context"
+	"github.com/angarcia/gorest/config"
 	"github.com/angarcia/gorest/handlers"
 	"github.com/angarcia/gorest/mw"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	httpSwagger "github.com/swaggo/http-swagger"
+	"github.com/urfave/cli/v2"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newApp Returns the gorest command line: serve and the client commands. Without a command it serves, accepting
+// the serve flags, as it did before the client commands existed.
+func newApp() *cli.App {
+	return &cli.App{
+		Name:   "gorest",
+		Usage:  "in-memory REST resource server and its command-line client",
+		Flags:  config.Flags(),
+		Action: serveAction,
+		Commands: append([]*cli.Command{{
+			Name:   "serve",
+			Usage:  "run the server",
+			Flags:  config.Flags(),
+			Action: serveAction,
+		}}, clientCommands()...),
+	}
+}
+
+// serveAction Loads the configuration and serves until SIGINT/SIGTERM.
+func serveAction(c *cli.Context) error {
+	cfg, err := config.Load(c)
+	if err != nil {
+		return err
+	}
+
+	// The first SIGINT/SIGTERM drains the server; a second one kills it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return run(ctx, cfg)
+}
 
-	// Port Configuration & HTTP Logger Initiate
-	var port string = ":8181"
+// run Serves the API configured by cfg until ctx is cancelled or the server fails.
+func run(ctx context.Context, cfg *config.Config) error {
+
+	// Access Logger Initiate
 	router := mux.NewRouter().StrictSlash(true)
-	router.Use(mw.HTTPLogger)
+	logger, err := mw.NewLogger(cfg.Log.Format, os.Stderr)
+	if err != nil {
+		return err
+	}
+	accessLog := mw.AccessLog(mw.AccessLogOptions{Logger: logger, SampleRate: cfg.Log.Sample, Redact: cfg.Log.Redact})
+
+	// Prometheus Metrics: HTTP middleware plus store instrumentation, exposed on /metrics.
+	reg := prometheus.NewRegistry()
+	var metrics func(http.Handler) http.Handler
+	if cfg.Metrics.Enabled {
+		reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		if metrics, err = mw.Metrics(reg); err != nil {
+			return err
+		}
+		router.Use(metrics)
+	}
+
+	// OpenTelemetry Tracing: server span per request continuing any W3C traceparent.
+	tp, shutdownTracing, err := newTracerProvider(cfg.Trace)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+	router.Use(mw.Tracing(tp))
+
+	if cfg.Metrics.Enabled {
+		router.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{})).Methods(http.MethodGet)
+	}
 
 	// Create handler which will also initialize empty map for storage.
 	rh := handlers.CreateHandler(make(map[string]map[string]interface{}))
+	if cfg.Resources.SoftDelete {
+		rh.EnableSoftDelete(cfg.Resources.TrashRetention.Duration())
+	}
+	if cfg.Resources.Ownership {
+		rh.EnableOwnership(cfg.Resources.AdminRole)
+	}
+	rh.SetDefaultTTL(cfg.Resources.DefaultTTL.Duration())
+	rh.SetHistorySize(cfg.Resources.HistorySize)
+	stopSweeper := rh.StartSweeper(time.Second)
+	if cfg.Metrics.Enabled {
+		if err := rh.Instrument(reg); err != nil {
+			return err
+		}
+	}
+	rh.Trace(tp)
+
+	// Rate Limiting: per-client token buckets, rejected with the JSON error format of the handlers.
+	limitKey := mw.KeyByIP
+	switch {
+	case cfg.RateLimit.Key == "apikey":
+		ac, err := cfg.AuthConfig()
+		if err != nil {
+			return err
+		}
+		limitKey = mw.KeyByAPIKey(ac.APIKeys)
+	case strings.HasPrefix(cfg.RateLimit.Key, "header:"):
+		limitKey = mw.KeyByHeader(strings.TrimPrefix(cfg.RateLimit.Key, "header:"))
+	}
+	// Probes and metrics are exempt, so orchestrators and scrapers are never throttled under load.
+	limitRules := []mw.RateLimitRule{{Route: "/livez"}, {Route: "/healthz"}, {Route: "/readyz"}, {Route: "/metrics"}}
+	for _, rule := range cfg.RateLimit.Rules {
+		limitRules = append(limitRules, mw.RateLimitRule{Route: rule.Route, Method: strings.ToUpper(rule.Method), Limit: mw.RateLimit{Rate: rule.Rate, Burst: rule.Burst}})
+	}
+	var limitStore mw.BucketStore
+	if cfg.RateLimit.Store == "shared" {
+		limitStore = rh.Buckets()
+	}
+	router.Use(mw.RateLimiter(mw.RateLimitOptions{
+		Default: mw.RateLimit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst},
+		Rules:   limitRules,
+		Key:     limitKey,
+		Store:   limitStore,
+		Error:   (&handlers.CommonHandler{}).HttpError,
+	}))
+
+	// Webhook dispatcher fed by every change made through the resource handler.
+	wd := handlers.CreateWebhookDispatcher(rh.Events(), 4)
+	wd.Access = rh.CanAccess
+	wd.AllowPrivate = cfg.Webhooks.AllowPrivate
+	wh := handlers.CreateWebhookHandler(wd)
+
+	// Liveness, health and readiness probes; readiness stays off until serving and turns off before shutdown.
+	hh := handlers.CreateHealthHandler()
+	hh.SetTimeout(cfg.Health.Timeout.Duration())
+	hh.AddCheck("store", rh.Ping)
+	if cfg.Health.Disk != "" {
+		hh.AddCheck("disk", handlers.DiskCheck(cfg.Health.Disk))
+	}
+
+	// OpenAPI document of every route, generated once they are all registered, browsable with Swagger UI.
+	var specJSON []byte
+	router.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(specJSON)
+	}).Methods(http.MethodGet)
+	router.PathPrefix("/swagger/").Handler(httpSwagger.Handler(httpSwagger.URL("/openapi.json")))
+
+	// API Route Definitions: /api/... and, with tenancy, the same routes scoped to a tenant under /t/{tenant}/api/...
+	apis := []*mux.Router{router.PathPrefix("/api/").Subrouter()}
+	if cfg.Tenancy.Enabled {
+		rh.EnableTenancy(handlers.TenantQuota{MaxTenants: cfg.Tenancy.MaxTenants, MaxResources: cfg.Tenancy.MaxResources}, cfg.Resources.AdminRole)
+		rh.OnTenantDeleted(wd.DropTenant)
+		router.Use(mw.Tenant(mw.TenantOptions{
+			Header: cfg.Tenancy.Header,
+			Domain: cfg.Tenancy.Domain,
+			Error:  (&handlers.CommonHandler{}).HttpError,
+		}))
+		apis = append(apis, router.PathPrefix("/t/{tenant}/api/").Subrouter())
+	}
+	if cfg.Auth.Enabled() || cfg.TLS.Mutual() {
+		ac, err := cfg.AuthConfig()
+		if err != nil {
+			return err
+		}
+		// With mutual TLS a verified client certificate always identifies the principal; without other
+		// authentication configured, requests without one stay anonymous.
+		if cfg.TLS.Mutual() && ac.ClientCert == nil {
+			ac.ClientCert = &mw.ClientCertConfig{}
+			ac.Optional = ac.Optional || !cfg.Auth.Enabled()
+		}
+		auths, err := ac.Authenticators()
+		if err != nil {
+			return err
+		}
+		for _, api := range apis {
+			api.Use(mw.Authentication(mw.AuthOptions{
+				Authenticators: auths,
+				Optional:       ac.Optional,
+				Error:          (&handlers.CommonHandler{}).HttpError,
+			}))
+		}
+	}
+	if cfg.Auth.Policy != "" {
+		policy, err := mw.LoadPolicy(cfg.Auth.Policy)
+		if err != nil {
+			return err
+		}
+		for _, api := range apis {
+			api.Use(mw.Authorization(policy, (&handlers.CommonHandler{}).HttpError))
+		}
+	}
+	routes(router, apis, rh, wh, hh, cfg.Tenancy.Enabled)
 
-	// API Route Definitions
-	api := router.PathPrefix("/api/").Subrouter()
-	api.HandleFunc("/resources/{id}", rh.GetResourceHandler).Methods(http.MethodGet)
-	api.HandleFunc("/resources", rh.GetResourcesHandler).Methods(http.MethodGet)
-	api.HandleFunc("/resources", rh.CreateResourceHandler).Methods(http.MethodPost)
-	api.HandleFunc("/resources/{id}", rh.UpdateResourceHandler).Methods(http.MethodPut)
-	api.HandleFunc("/resources/{id}", rh.DeleteResourceHandler).Methods(http.MethodDelete)
+	if specJSON, err = spec(router, cfg.Resources.Schema); err != nil {
+		return err
+	}
 
 	// Page Not Found Route Definition
 	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error: The page request was not found.")
 		http.Error(w, "PAGE NOT FOUND", http.StatusNotFound)
 	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	// Router.Use middleware skips unmatched requests, so metrics wrap these handlers to count them too.
+	if metrics != nil {
+		router.NotFoundHandler = metrics(router.NotFoundHandler)
+		router.MethodNotAllowedHandler = metrics(router.MethodNotAllowedHandler)
+	}
 
-	// Server Start
-	log.Printf("Starting Server: '%s'", port)
-	err := http.ListenAndServe(port, router)
-	if err != nil {
-		log.Fatal(err)
+	// Server Start: request ids and the access log wrap the whole router so unmatched routes are logged too.
+	var handler http.Handler = router
+	if cfg.Limits.MaxBodyBytes > 0 {
+		handler = http.MaxBytesHandler(handler, cfg.Limits.MaxBodyBytes)
+	}
+	if cfg.Log.Enabled {
+		handler = accessLog(handler)
 	}
+	srv := &http.Server{Addr: cfg.Listen, Handler: mw.RequestID(handler)}
+	if cfg.TLS.Enabled() {
+		if srv.TLSConfig, err = cfg.TLS.ServerConfig(); err != nil {
+			return err
+		}
+	}
+	srv.RegisterOnShutdown(rh.Close)
+	err = serve(ctx, srv, hh, cfg.Shutdown.Delay.Duration(), cfg.Shutdown.Timeout.Duration())
+
+	// Background writers stop once no request can reach the store anymore.
+	stopSweeper()
+	drain, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout.Duration())
+	defer cancel()
+	wd.Close(drain)
+	return err
 }
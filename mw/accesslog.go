@@ -0,0 +1,144 @@
+package mw
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Redacted Replacement value for redacted fields.
+const Redacted = "[REDACTED]"
+
+// AccessLogOptions Configuration for AccessLog.
+type AccessLogOptions struct {
+	// Logger receives one record per request. If nil, JSON records are written to stderr.
+	Logger *slog.Logger
+	// SampleRate is the fraction of non-error requests logged, in (0, 1]. Zero logs every request.
+	// Responses with status 400 and above are always logged.
+	SampleRate float64
+	// Redact lists logged fields (e.g. "remote", "user_agent") or query parameters whose values are hidden.
+	Redact []string
+}
+
+// NewLogger Returns a slog logger writing to w in "json" or "text" (logfmt) format.
+func NewLogger(format string, w io.Writer) (*slog.Logger, error) {
+	switch format {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	case "text", "logfmt":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	}
+	return nil, errors.New("log format must be json, text or logfmt")
+}
+
+// responseRecorder Wraps a ResponseWriter to capture status code and body size.
+// Flush and Hijack are passed through so streaming and WebSocket handlers keep working.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.status == 0 {
+		rr.status = code
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if rr.status == 0 {
+		rr.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap Exposes the underlying writer to http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// AccessLog Middleware structured access logger recording the outcome of every request.
+func AccessLog(opts AccessLogOptions) func(http.Handler) http.Handler {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
+	redact := make(map[string]bool, len(opts.Redact))
+	for _, f := range opts.Redact {
+		redact[f] = true
+	}
+	field := func(name, v string) slog.Attr {
+		if redact[name] && v != "" {
+			v = Redacted
+		}
+		return slog.String(name, v)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rr := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rr, r)
+
+			if rr.status == 0 {
+				rr.status = http.StatusOK
+			}
+			if rr.status < 400 && opts.SampleRate > 0 && opts.SampleRate < 1 && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			u := *r.URL
+			if q := u.Query(); len(q) > 0 {
+				for k := range q {
+					if redact[k] {
+						q.Set(k, Redacted)
+					}
+				}
+				u.RawQuery = q.Encode()
+			}
+
+			level := slog.LevelInfo
+			if rr.status >= 500 {
+				level = slog.LevelError
+			} else if rr.status >= 400 {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				field("path", u.RequestURI()),
+				slog.Int("status", rr.status),
+				slog.Int("bytes", rr.bytes),
+				slog.Duration("duration", time.Since(start)),
+				field("remote", r.RemoteAddr),
+				field("user_agent", r.UserAgent()),
+				field("request_id", RequestIDFrom(r.Context())),
+			)
+		})
+	}
+}
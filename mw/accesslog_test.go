@@ -0,0 +1,96 @@
+package mw
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAccessLog Captured fields, sampling and redaction.
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   AccessLogOptions
+		target string
+		status int
+		logged bool
+		want   map[string]interface{}
+	}{
+		{
+			name:   "AccessLog - Success",
+			target: "/api/resources?limit=5",
+			status: http.StatusCreated,
+			logged: true,
+			want:   map[string]interface{}{"method": "GET", "path": "/api/resources?limit=5", "status": float64(201), "bytes": float64(5), "user_agent": "tester", "request_id": "abc"},
+		},
+		{
+			name:   "AccessLog - Redacted",
+			opts:   AccessLogOptions{Redact: []string{"token", "remote"}},
+			target: "/api/resources?token=secret",
+			status: http.StatusOK,
+			logged: true,
+			want:   map[string]interface{}{"path": "/api/resources?token=%5BREDACTED%5D", "remote": Redacted},
+		},
+		{
+			name:   "AccessLog - Sampled Out",
+			opts:   AccessLogOptions{SampleRate: 0.0000001},
+			target: "/api/resources",
+			status: http.StatusOK,
+			logged: false,
+		},
+		{
+			name:   "AccessLog - Errors Always Logged",
+			opts:   AccessLogOptions{SampleRate: 0.0000001},
+			target: "/api/resources",
+			status: http.StatusInternalServerError,
+			logged: true,
+			want:   map[string]interface{}{"level": "ERROR", "status": float64(500)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.opts.Logger, _ = NewLogger("json", &buf)
+			h := RequestID(AccessLog(tt.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte("hello"))
+			})))
+
+			r := httptest.NewRequest("GET", tt.target, nil)
+			r.Header.Set("User-Agent", "tester")
+			r.Header.Set("X-Request-ID", "abc")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if (buf.Len() > 0) != tt.logged {
+				t.Fatalf("got log %q want logged %v", buf.String(), tt.logged)
+			}
+			if !tt.logged {
+				return
+			}
+			rec := map[string]interface{}{}
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if rec[k] != v {
+					t.Errorf("%s: got %v want %v", k, rec[k], v)
+				}
+			}
+		})
+	}
+}
+
+// TestResponseRecorder_Flush Streaming handlers still see an http.Flusher.
+func TestResponseRecorder_Flush(t *testing.T) {
+	h := AccessLog(AccessLogOptions{Logger: nil})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("wrapped writer is not a Flusher")
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Errorf("wrapped writer is not a Hijacker")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
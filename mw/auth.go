@@ -0,0 +1,218 @@
+package mw
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Principal The authenticated caller of a request.
+type Principal struct {
+	Subject string                 `json:"subject"`
+	Method  string                 `json:"method"`
+	Roles   []string               `json:"roles,omitempty"`
+	Groups  []string               `json:"groups,omitempty"`
+	Claims  map[string]interface{} `json:"claims,omitempty"`
+}
+
+// HasRole Reports whether p carries role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom Returns the principal stored in ctx by Authentication, or nil for anonymous requests.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey).(*Principal)
+	return p
+}
+
+// WithPrincipal Returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// ErrInvalidCredentials Credentials were presented but could not be verified.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator One authentication scheme. Authenticate returns a nil principal and nil error when the request
+// carries no credentials for the scheme, so the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthOptions Configuration for Authentication.
+type AuthOptions struct {
+	Authenticators []Authenticator
+	// Optional lets requests without credentials through anonymously. Invalid credentials are always rejected.
+	Optional bool
+	// Error writes the 401 response; http.Error is used when nil.
+	Error func(w http.ResponseWriter, err string, code int)
+}
+
+// Authentication Middleware trying each authenticator in order and storing the first principal found in the
+// request context.
+func Authentication(opts AuthOptions) func(http.Handler) http.Handler {
+	if opts.Error == nil {
+		opts.Error = http.Error
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range opts.Authenticators {
+				p, err := a.Authenticate(r)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="gorest", Basic realm="gorest"`)
+					opts.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				if p != nil {
+					next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+					return
+				}
+			}
+			if !opts.Optional {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gorest", Basic realm="gorest"`)
+				opts.Error(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKey A static key, stored as the hex SHA-256 of its value.
+type APIKey struct {
+	Hash    string   `json:"hash"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+}
+
+// HashAPIKey Returns the value to store in APIKey.Hash for key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuthenticator Authenticates the X-API-Key header (or Header, if set) against hashed keys.
+type APIKeyAuthenticator struct {
+	Header string
+	Keys   []APIKey
+}
+
+// Authenticate Implements Authenticator.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	v := r.Header.Get(header)
+	if v == "" {
+		return nil, nil
+	}
+
+	sum := HashAPIKey(v)
+	for _, k := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(sum), []byte(strings.ToLower(k.Hash))) == 1 {
+			return &Principal{Subject: k.Subject, Method: "apikey", Roles: k.Roles, Groups: k.Groups}, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// BasicUser An HTTP Basic user with a bcrypt password hash.
+type BasicUser struct {
+	Username string   `json:"username"`
+	Hash     string   `json:"hash"`
+	Roles    []string `json:"roles,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// BasicAuthenticator Authenticates HTTP Basic credentials.
+type BasicAuthenticator struct {
+	Users []BasicUser
+}
+
+// dummyHash bcrypt hash at the default cost compared against when no user matches, so unknown usernames take as
+// long to reject as wrong passwords and response times do not reveal which usernames exist.
+var dummyHash = []byte("$2a$10$noCJRxcUY5r/3oZtSAlb9e05l9W5Ruhy3/594OfXUfKk.dQt/yIJu")
+
+// Authenticate Implements Authenticator.
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	for _, u := range a.Users {
+		if u.Username != user {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(pass)) != nil {
+			return nil, ErrInvalidCredentials
+		}
+		return &Principal{Subject: u.Username, Method: "basic", Roles: u.Roles, Groups: u.Groups}, nil
+	}
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(pass))
+	return nil, ErrInvalidCredentials
+}
+
+// AuthConfig File representation of the authentication setup.
+type AuthConfig struct {
+	Optional   bool              `json:"optional"`
+	ClientCert *ClientCertConfig `json:"clientCert,omitempty"`
+	APIKeys    []APIKey          `json:"apiKeys,omitempty"`
+	Basic      []BasicUser       `json:"basic,omitempty"`
+	JWT        *JWTConfig        `json:"jwt,omitempty"`
+}
+
+// LoadAuthConfig Reads an AuthConfig from a JSON file.
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &AuthConfig{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Authenticators Builds the configured authenticators: client certificates, then API keys, then JWT bearer
+// tokens, then HTTP Basic.
+func (c *AuthConfig) Authenticators() ([]Authenticator, error) {
+	var auths []Authenticator
+	if c.ClientCert != nil {
+		auths = append(auths, &ClientCertAuthenticator{Roles: c.ClientCert.Roles})
+	}
+	if len(c.APIKeys) > 0 {
+		auths = append(auths, &APIKeyAuthenticator{Keys: c.APIKeys})
+	}
+	if c.JWT != nil {
+		a, err := c.JWT.Authenticator()
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, a)
+	}
+	if len(c.Basic) > 0 {
+		auths = append(auths, &BasicAuthenticator{Users: c.Basic})
+	}
+	if len(auths) == 0 {
+		return nil, errors.New("auth: no authentication method configured")
+	}
+	return auths, nil
+}
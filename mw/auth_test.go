@@ -0,0 +1,103 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestAuthentication API keys, HTTP Basic, anonymous access and rejections.
+func TestAuthentication(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	auths := []Authenticator{
+		&APIKeyAuthenticator{Keys: []APIKey{{Hash: HashAPIKey("secret-key"), Subject: "ci", Roles: []string{"writer"}}}},
+		&BasicAuthenticator{Users: []BasicUser{{Username: "bruce", Hash: string(hash), Roles: []string{"admin"}}}},
+	}
+
+	tests := []struct {
+		name     string
+		optional bool
+		apiKey   string
+		user     string
+		pass     string
+		want     int
+		subject  string
+	}{
+		{name: "Authentication - API Key Success", apiKey: "secret-key", want: 200, subject: "ci"},
+		{name: "Authentication - Basic Success", user: "bruce", pass: "hunter2", want: 200, subject: "bruce"},
+		{name: "Authentication - Anonymous Optional Success", optional: true, want: 200},
+		{name: "Authentication - Missing Credentials Failure", want: 401},
+		{name: "Authentication - Wrong API Key Failure", apiKey: "other-key", want: 401},
+		{name: "Authentication - Wrong Password Failure", user: "bruce", pass: "wrong", want: 401},
+		{name: "Authentication - Unknown User Failure", user: "joker", pass: "hunter2", want: 401},
+		{name: "Authentication - Wrong Password Optional Failure", optional: true, user: "bruce", pass: "wrong", want: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Principal
+			h := Authentication(AuthOptions{Authenticators: auths, Optional: tt.optional})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = PrincipalFrom(r.Context()) }))
+
+			r := httptest.NewRequest("GET", "/api/resources", nil)
+			if tt.apiKey != "" {
+				r.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if w.Code == 401 && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+			if tt.subject != "" && (got == nil || got.Subject != tt.subject) {
+				t.Errorf("got principal %+v want subject %q", got, tt.subject)
+			}
+			if tt.subject == "" && got != nil {
+				t.Errorf("got principal %+v want none", got)
+			}
+		})
+	}
+}
+
+// TestBasicAuthenticator_dummyHash Unknown users are compared against a valid hash at the default cost, so they
+// take as long to reject as known ones.
+func TestBasicAuthenticator_dummyHash(t *testing.T) {
+	cost, err := bcrypt.Cost(dummyHash)
+	if err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("got cost %d, %v want %d", cost, err, bcrypt.DefaultCost)
+	}
+}
+
+// TestLoadAuthConfig Authenticators are built in order from the config file.
+func TestLoadAuthConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	os.WriteFile(path, []byte(`{"apiKeys":[{"hash":"`+HashAPIKey("k")+`","subject":"ci"}],"jwt":{"secret":"s","issuer":"gorest"}}`), 0o600)
+
+	c, err := LoadAuthConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auths, err := c.Authenticators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auths) != 2 {
+		t.Fatalf("got %d authenticators want 2", len(auths))
+	}
+	if _, ok := auths[1].(*JWTAuthenticator); !ok {
+		t.Errorf("got %T want *JWTAuthenticator", auths[1])
+	}
+
+	if _, err := (&AuthConfig{}).Authenticators(); err == nil {
+		t.Errorf("empty config: got nil error")
+	}
+}
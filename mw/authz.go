@@ -0,0 +1,147 @@
+package mw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gorilla/mux"
+)
+
+// AnonymousRole Role given to requests without a principal, so policies can grant anonymous access explicitly.
+const AnonymousRole = "anonymous"
+
+// PolicyRule Allows principals holding any of Roles to call Methods on Collections. "*" matches anything.
+type PolicyRule struct {
+	Roles       []string `json:"roles"`
+	Collections []string `json:"collections"`
+	Methods     []string `json:"methods"`
+}
+
+// Policy Role-based access policy. Requests are denied unless some rule allows them. For example:
+//
+//	rules:
+//	  - roles: [reader, writer, admin]
+//	    collections: [resources]
+//	    methods: [GET]
+//	  - roles: [writer, admin]
+//	    collections: [resources]
+//	    methods: [POST, PUT]
+//	  - roles: [admin]
+//	    collections: ["*"]
+//	    methods: ["*"]
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// LoadPolicy Reads a Policy from a YAML (or JSON) file.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	for n, rule := range p.Rules {
+		if len(rule.Roles) == 0 || len(rule.Collections) == 0 || len(rule.Methods) == 0 {
+			return nil, fmt.Errorf("policy %s: rule %d needs roles, collections and methods", path, n)
+		}
+	}
+	return p, nil
+}
+
+// Allowed Reports whether a principal with roles may call method on collection.
+func (p *Policy) Allowed(roles []string, collection, method string) bool {
+	for _, rule := range p.Rules {
+		if matchAny(rule.Collections, collection) && matchAny(rule.Methods, method) {
+			for _, role := range roles {
+				if matchAny(rule.Roles, role) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchAny Reports whether v is in list, case-insensitively, or list contains "*".
+func matchAny(list []string, v string) bool {
+	for _, s := range list {
+		if s == "*" || strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collection Returns the collection addressed by r: the first path segment of its gorilla/mux route template
+// after /api/, e.g. "resources" for /api/resources/{id} and /t/{tenant}/api/resources/{id}.
+func Collection(r *http.Request) string {
+	path := r.URL.Path
+	if cr := mux.CurrentRoute(r); cr != nil {
+		if tpl, err := cr.GetPathTemplate(); err == nil {
+			path = tpl
+		}
+	}
+	if n := strings.Index(path, "/api/"); n >= 0 {
+		path = path[n+len("/api/"):]
+	} else {
+		path = strings.TrimPrefix(path, "/")
+	}
+	if n := strings.IndexByte(path, '/'); n >= 0 {
+		path = path[:n]
+	}
+	return path
+}
+
+// check Returns 401 or 403 with the reason unless the principal of r may call method on collection.
+func (p *Policy) check(r *http.Request, collection, method string) (int, error) {
+	roles := []string{AnonymousRole}
+	pr := PrincipalFrom(r.Context())
+	if pr != nil {
+		roles = pr.Roles
+	}
+	if p.Allowed(roles, collection, method) {
+		return 0, nil
+	}
+	if pr == nil {
+		return http.StatusUnauthorized, errors.New("authentication required")
+	}
+	return http.StatusForbidden, fmt.Errorf("%s may not %s %s", pr.Subject, method, collection)
+}
+
+// Authorize Checks the principal of r against the policy Authorization enforced on r, for handlers acting on a
+// collection or with a method other than those of their route. It returns 401 or 403 with the reason when the
+// call is denied, and allows everything when r passed through no Authorization.
+func Authorize(r *http.Request, collection, method string) (int, error) {
+	p, _ := r.Context().Value(policyKey).(*Policy)
+	if p == nil {
+		return 0, nil
+	}
+	return p.check(r, collection, method)
+}
+
+// Authorization Middleware enforcing policy on the principal stored by Authentication. Denied requests get 403,
+// or 401 when made anonymously. It must run after Authentication and be installed with Router.Use.
+// Allowed requests carry the policy on to Authorize.
+func Authorization(policy *Policy, httpError func(w http.ResponseWriter, err string, code int)) func(http.Handler) http.Handler {
+	if httpError == nil {
+		httpError = http.Error
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if code, err := policy.check(r, Collection(r), r.Method); err != nil {
+				httpError(w, err.Error(), code)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), policyKey, policy)))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const testPolicy = `
+rules:
+  - roles: [reader, writer, admin]
+    collections: [resources]
+    methods: [GET]
+  - roles: [writer, admin]
+    collections: [resources]
+    methods: [POST, PUT]
+  - roles: [admin]
+    collections: ["*"]
+    methods: ["*"]
+  - roles: [anonymous]
+    collections: [webhooks]
+    methods: [GET]
+`
+
+// TestAuthorization Role, collection and method checks on routes of the api subrouter.
+func TestAuthorization(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte(testPolicy), 0o600)
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/").Subrouter()
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if role := r.Header.Get("Role"); role != "" {
+				r = r.WithContext(WithPrincipal(r.Context(), &Principal{Subject: "tester", Roles: []string{role}}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	api.Use(Authorization(policy, nil))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	api.HandleFunc("/resources", ok).Methods(http.MethodGet, http.MethodPost)
+	api.HandleFunc("/resources/{id}", ok).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	api.HandleFunc("/webhooks", ok).Methods(http.MethodGet)
+
+	tests := []struct {
+		name   string
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "Authorization - Reader Get Success", role: "reader", method: "GET", path: "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200},
+		{name: "Authorization - Reader Post Failure", role: "reader", method: "POST", path: "/api/resources", want: 403},
+		{name: "Authorization - Writer Put Success", role: "writer", method: "PUT", path: "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200},
+		{name: "Authorization - Writer Delete Failure", role: "writer", method: "DELETE", path: "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 403},
+		{name: "Authorization - Admin Delete Success", role: "admin", method: "DELETE", path: "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497", want: 200},
+		{name: "Authorization - Unknown Role Failure", role: "guest", method: "GET", path: "/api/resources", want: 403},
+		{name: "Authorization - Anonymous Failure", method: "GET", path: "/api/resources", want: 401},
+		{name: "Authorization - Anonymous Rule Success", method: "GET", path: "/api/webhooks", want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Role", tt.role)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadPolicy Incomplete rules are rejected.
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte("rules:\n  - roles: [admin]\n    methods: [GET]\n"), 0o600)
+	if _, err := LoadPolicy(path); err == nil {
+		t.Errorf("got nil error for rule without collections")
+	}
+}
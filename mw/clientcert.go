@@ -0,0 +1,41 @@
+package mw
+
+import (
+	"net/http"
+)
+
+// ClientCertConfig File representation of a ClientCertAuthenticator.
+type ClientCertConfig struct {
+	// Roles maps a certificate subject common name to the roles of its principal.
+	Roles map[string][]string `json:"roles,omitempty"`
+}
+
+// ClientCertAuthenticator Authenticates requests by the client certificate verified during the TLS handshake.
+// The principal's subject is the certificate's common name and its groups are the organizational units.
+type ClientCertAuthenticator struct {
+	Roles map[string][]string
+}
+
+// Authenticate Implements Authenticator. Only certificates that passed verification against the client CA
+// bundle are accepted; the server must be configured to verify them.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Subject: cert.Subject.CommonName,
+		Method:  "mtls",
+		Roles:   a.Roles[cert.Subject.CommonName],
+		Groups:  cert.Subject.OrganizationalUnit,
+		Claims: map[string]interface{}{
+			"subject": cert.Subject.String(),
+			"issuer":  cert.Issuer.String(),
+			"serial":  cert.SerialNumber.String(),
+		},
+	}, nil
+}
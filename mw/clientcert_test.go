@@ -0,0 +1,70 @@
+package mw
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestClientCertAuthenticator Verified client certificates become principals.
+func TestClientCertAuthenticator(t *testing.T) {
+	a := &ClientCertAuthenticator{Roles: map[string][]string{"ci": {"writer"}}}
+	cert := func(cn string, ou ...string) *tls.ConnectionState {
+		c := &x509.Certificate{SerialNumber: big.NewInt(7), Subject: pkix.Name{CommonName: cn, OrganizationalUnit: ou}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{c}}}
+	}
+
+	tests := []struct {
+		name    string
+		tls     *tls.ConnectionState
+		subject string
+		roles   int
+		groups  int
+		err     bool
+	}{
+		{name: "ClientCert - Success", tls: cert("ci", "build", "release"), subject: "ci", roles: 1, groups: 2},
+		{name: "ClientCert - Unmapped Subject Success", tls: cert("bruce"), subject: "bruce"},
+		{name: "ClientCert - Plain HTTP Success"},
+		{name: "ClientCert - Unverified Success", tls: &tls.ConnectionState{}},
+		{name: "ClientCert - Missing Common Name Failure", tls: cert(""), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/resources", nil)
+			r.TLS = tt.tls
+
+			p, err := a.Authenticate(r)
+			if tt.err != (err != nil) {
+				t.Fatalf("got error %v want error %v", err, tt.err)
+			}
+			if tt.subject == "" {
+				if p != nil {
+					t.Errorf("got principal %+v want none", p)
+				}
+				return
+			}
+			if p == nil || p.Subject != tt.subject || p.Method != "mtls" || len(p.Roles) != tt.roles || len(p.Groups) != tt.groups {
+				t.Errorf("got principal %+v want subject %q", p, tt.subject)
+			}
+		})
+	}
+}
+
+// TestClientCertAuthenticator_Authentication A verified certificate authenticates without other credentials.
+func TestClientCertAuthenticator_Authentication(t *testing.T) {
+	var got *Principal
+	h := Authentication(AuthOptions{Authenticators: []Authenticator{&ClientCertAuthenticator{}}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = PrincipalFrom(r.Context()) }))
+
+	r := httptest.NewRequest("GET", "/api/resources", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ci"}, SerialNumber: big.NewInt(1)}}}}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 200 || got == nil || got.Subject != "ci" {
+		t.Errorf("got %d principal %+v want 200 and subject ci", w.Code, got)
+	}
+}
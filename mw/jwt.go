@@ -0,0 +1,234 @@
+package mw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtMethods Signing algorithms accepted for bearer tokens. "none" is never accepted.
+var jwtMethods = []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWK One key of a JSON Web Key Set. Only the members needed for verification are read.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// Key Returns the verification key: *rsa.PublicKey, *ecdsa.PublicKey or []byte for "oct" keys.
+func (k JWK) Key() (interface{}, error) {
+	b64 := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := b64(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: n: %w", k.Kid, err)
+		}
+		e, err := b64(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: e: %w", k.Kid, err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: x: %w", k.Kid, err)
+		}
+		y, err := b64(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: y: %w", k.Kid, err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("jwk %q: point not on curve", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.K, "="))
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: k: %w", k.Kid, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("jwk %q: unsupported key type %q", k.Kid, k.Kty)
+}
+
+// LoadJWKS Reads a JSON Web Key Set from path, returning the verification keys by key id.
+func LoadJWKS(path string) (map[string]interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.Key()
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// JWTAuthenticator Authenticates "Authorization: Bearer" tokens. The token's kid header selects the key;
+// tokens without one are accepted only when a single key is configured. Tokens must carry an expiry.
+type JWTAuthenticator struct {
+	Keys     map[string]interface{}
+	Issuer   string
+	Audience string
+	// RolesClaim and GroupsClaim name the claims copied into the principal; "roles" and "groups" by default.
+	RolesClaim  string
+	GroupsClaim string
+	Leeway      time.Duration
+}
+
+// Authenticate Implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(jwtMethods), jwt.WithExpirationRequired(), jwt.WithLeeway(a.Leeway)}
+	if a.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.Issuer))
+	}
+	if a.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(strings.TrimSpace(h[7:]), claims, a.key, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	rolesClaim, groupsClaim := a.RolesClaim, a.GroupsClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	return &Principal{
+		Subject: sub,
+		Method:  "jwt",
+		Roles:   stringsClaim(claims[rolesClaim]),
+		Groups:  stringsClaim(claims[groupsClaim]),
+		Claims:  claims,
+	}, nil
+}
+
+// key Resolves the verification key for t. The parser rejects keys whose type does not match the algorithm.
+func (a *JWTAuthenticator) key(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" && len(a.Keys) == 1 {
+		for _, k := range a.Keys {
+			return k, nil
+		}
+	}
+	k, ok := a.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return k, nil
+}
+
+// stringsClaim Accepts a claim holding either a list of strings or a single space-separated string.
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// JWTConfig File representation of a JWTAuthenticator. Keys come from the JWKS file and, for HMAC, Secret.
+type JWTConfig struct {
+	JWKS        string `json:"jwks,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+	Issuer      string `json:"issuer,omitempty"`
+	Audience    string `json:"audience,omitempty"`
+	RolesClaim  string `json:"rolesClaim,omitempty"`
+	GroupsClaim string `json:"groupsClaim,omitempty"`
+	Leeway      string `json:"leeway,omitempty"`
+}
+
+// Authenticator Builds the JWTAuthenticator described by c.
+func (c *JWTConfig) Authenticator() (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{Keys: map[string]interface{}{}, Issuer: c.Issuer, Audience: c.Audience,
+		RolesClaim: c.RolesClaim, GroupsClaim: c.GroupsClaim}
+	if c.JWKS != "" {
+		keys, err := LoadJWKS(c.JWKS)
+		if err != nil {
+			return nil, err
+		}
+		a.Keys = keys
+	}
+	if c.Secret != "" {
+		a.Keys[""] = []byte(c.Secret)
+	}
+	if len(a.Keys) == 0 {
+		return nil, errors.New("jwt: no verification keys configured")
+	}
+	if c.Leeway != "" {
+		d, err := time.ParseDuration(c.Leeway)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: leeway: %w", err)
+		}
+		a.Leeway = d
+	}
+	return a, nil
+}
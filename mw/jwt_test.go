@@ -0,0 +1,98 @@
+package mw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestJWTAuthenticator Signature algorithms, key selection and issuer/audience/expiry checks.
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks := `{"keys":[
+		{"kty":"RSA","kid":"rsa","n":"` + b64(rsaKey.N.Bytes()) + `","e":"` + b64(big.NewInt(int64(rsaKey.E)).Bytes()) + `"},
+		{"kty":"EC","kid":"ec","crv":"P-256","x":"` + b64(ecKey.X.Bytes()) + `","y":"` + b64(ecKey.Y.Bytes()) + `"},
+		{"kty":"oct","kid":"hmac","k":"` + b64([]byte("shared-secret")) + `"}]}`
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, []byte(jwks), 0o600)
+
+	a, err := (&JWTConfig{JWKS: path, Issuer: "gorest", Audience: "api"}).Authenticator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "bruce", "iss": "gorest", "aud": "api", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"}}
+	}
+	sign := func(m jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(m, claims)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	with := func(k string, v interface{}) jwt.MapClaims {
+		c := valid()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{name: "JWT - RSA Success", token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, valid()), want: true},
+		{name: "JWT - ECDSA Success", token: sign(jwt.SigningMethodES256, "ec", ecKey, valid()), want: true},
+		{name: "JWT - HMAC Success", token: sign(jwt.SigningMethodHS256, "hmac", []byte("shared-secret"), valid()), want: true},
+		{name: "JWT - Wrong HMAC Secret Failure", token: sign(jwt.SigningMethodHS256, "hmac", []byte("guess"), valid())},
+		{name: "JWT - Unknown Kid Failure", token: sign(jwt.SigningMethodHS256, "other", []byte("shared-secret"), valid())},
+		{name: "JWT - Algorithm Confusion Failure", token: sign(jwt.SigningMethodHS256, "rsa", rsaKey.PublicKey.N.Bytes(), valid())},
+		{name: "JWT - Expired Failure", token: sign(jwt.SigningMethodES256, "ec", ecKey, with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{name: "JWT - Missing Expiry Failure", token: sign(jwt.SigningMethodES256, "ec", ecKey, with("exp", nil))},
+		{name: "JWT - Wrong Issuer Failure", token: sign(jwt.SigningMethodES256, "ec", ecKey, with("iss", "evil"))},
+		{name: "JWT - Wrong Audience Failure", token: sign(jwt.SigningMethodES256, "ec", ecKey, with("aud", "other"))},
+		{name: "JWT - Missing Subject Failure", token: sign(jwt.SigningMethodES256, "ec", ecKey, with("sub", nil))},
+		{name: "JWT - Garbage Failure", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			p, err := a.Authenticate(r)
+			if tt.want && (err != nil || p == nil || p.Subject != "bruce" || !p.HasRole("admin")) {
+				t.Errorf("got %+v, %v want principal bruce with role admin", p, err)
+			}
+			if !tt.want && err == nil {
+				t.Errorf("got %+v want error", p)
+			}
+		})
+	}
+
+	p, err := a.Authenticate(httptest.NewRequest("GET", "/", nil))
+	if p != nil || err != nil {
+		t.Errorf("no credentials: got %+v, %v want nil, nil", p, err)
+	}
+}
@@ -0,0 +1,54 @@
+package mw
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics Middleware counting requests and observing their latency, labeled by gorilla/mux route template,
+// method and status. It must be installed with Router.Use so the matched route is known. Router.Use middleware
+// does not run for requests matching no route, so the router's NotFoundHandler and MethodNotAllowedHandler must be
+// wrapped with it too for those to be counted, as route "unmatched".
+func Metrics(reg prometheus.Registerer) (func(http.Handler) http.Handler, error) {
+	labels := []string{"route", "method", "status"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gorest_http_requests_total",
+		Help: "HTTP requests handled, by route template, method and status.",
+	}, labels)
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gorest_http_request_duration_seconds",
+		Help:    "HTTP request latency, by route template, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, labels)
+	for _, c := range []prometheus.Collector{requests, latency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := "unmatched"
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+
+			start := time.Now()
+			rr := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rr, r)
+			if rr.status == 0 {
+				rr.status = http.StatusOK
+			}
+
+			values := []string{route, r.Method, strconv.Itoa(rr.status)}
+			requests.WithLabelValues(values...).Inc()
+			latency.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+		})
+	}, nil
+}
@@ -0,0 +1,52 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetrics Requests are counted by route template rather than raw path.
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := Metrics(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.Use(metrics)
+	router.NotFoundHandler = metrics(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = metrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	router.HandleFunc("/api/resources/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "dummy" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}).Methods(http.MethodGet)
+
+	for _, target := range []string{"GET /api/resources/a", "GET /api/resources/b", "GET /api/resources/dummy", "GET /nope", "DELETE /api/resources/a"} {
+		method, path, _ := strings.Cut(target, " ")
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+
+	want := `
+# HELP gorest_http_requests_total HTTP requests handled, by route template, method and status.
+# TYPE gorest_http_requests_total counter
+gorest_http_requests_total{method="DELETE",route="unmatched",status="405"} 1
+gorest_http_requests_total{method="GET",route="/api/resources/{id}",status="200"} 2
+gorest_http_requests_total{method="GET",route="/api/resources/{id}",status="400"} 1
+gorest_http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "gorest_http_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(reg, "gorest_http_request_duration_seconds"); n != 4 {
+		t.Errorf("got %d latency series want %d", n, 4)
+	}
+}
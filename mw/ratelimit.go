@@ -0,0 +1,196 @@
+package mw
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RateLimit Token bucket refilled at Rate tokens per second holding at most Burst tokens.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitRule Overrides the default limit for requests matching a route template and/or method.
+// Empty Route or Method match anything; the first matching rule wins. A zero Limit exempts matching requests.
+type RateLimitRule struct {
+	Route  string
+	Method string
+	Limit  RateLimit
+}
+
+// BucketStore Holds token buckets. MemoryBucketStore keeps them in the limiter itself; a store backed by shared
+// storage lets several limiters enforce one limit.
+type BucketStore interface {
+	// Take removes one token from the bucket named key if available, reporting the tokens left afterwards
+	// and how long until the next token is available.
+	Take(key string, limit RateLimit, now time.Time) (ok bool, remaining int, wait time.Duration)
+}
+
+// RateLimitOptions Configuration for RateLimiter.
+type RateLimitOptions struct {
+	Default RateLimit
+	Rules   []RateLimitRule
+	// Key identifies the client; KeyByIP is used when nil.
+	Key func(*http.Request) string
+	// Store holds the buckets; a MemoryBucketStore is used when nil.
+	Store BucketStore
+	// Error writes the 429 response; http.Error is used when nil.
+	Error func(w http.ResponseWriter, err string, code int)
+}
+
+// KeyByIP Identifies clients by the host part of the remote address.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader Identifies clients by the value of header name, falling back to the client IP when absent.
+func KeyByHeader(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return name + ":" + v
+		}
+		return KeyByIP(r)
+	}
+}
+
+// KeyByAPIKey Identifies clients by the hash of their X-API-Key header when it is one of keys, falling back to the
+// client IP otherwise, so made-up keys neither escape the limit nor add buckets and raw keys are never held.
+func KeyByAPIKey(keys []APIKey) func(*http.Request) string {
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[strings.ToLower(k.Hash)] = true
+	}
+	return func(r *http.Request) string {
+		if v := r.Header.Get("X-API-Key"); v != "" {
+			if sum := HashAPIKey(v); known[sum] {
+				return "apikey:" + sum
+			}
+		}
+		return KeyByIP(r)
+	}
+}
+
+// Bucket State of one token bucket, for BucketStore implementations.
+type Bucket struct {
+	Tokens float64
+	Last   time.Time
+	Limit  RateLimit
+}
+
+// NewBucket Returns a full bucket for limit at now.
+func NewBucket(limit RateLimit, now time.Time) *Bucket {
+	return &Bucket{Tokens: float64(limit.Burst), Last: now, Limit: limit}
+}
+
+// Take Refills b up to now under limit and removes one token if available, reporting the tokens left afterwards
+// and how long until the next token is available.
+func (b *Bucket) Take(limit RateLimit, now time.Time) (bool, int, time.Duration) {
+	b.Limit = limit
+	b.Tokens = math.Min(float64(limit.Burst), b.Tokens+now.Sub(b.Last).Seconds()*limit.Rate)
+	b.Last = now
+
+	if b.Tokens < 1 {
+		return false, 0, time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+	}
+	b.Tokens--
+	return true, int(b.Tokens), 0
+}
+
+// Idle Reports whether b has refilled completely by now, so dropping it changes nothing.
+func (b *Bucket) Idle(now time.Time) bool {
+	return now.Sub(b.Last).Seconds()*b.Limit.Rate >= float64(b.Limit.Burst)
+}
+
+// MemoryBucketStore Process-local BucketStore. Idle buckets are evicted periodically.
+type MemoryBucketStore struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+	sweep   time.Time
+}
+
+// NewMemoryBucketStore Returns an empty in-memory bucket store.
+func NewMemoryBucketStore() *MemoryBucketStore {
+	return &MemoryBucketStore{buckets: make(map[string]*Bucket)}
+}
+
+// Take Implements BucketStore.
+func (s *MemoryBucketStore) Take(key string, limit RateLimit, now time.Time) (bool, int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.sweep) > time.Minute {
+		for k, b := range s.buckets {
+			if b.Idle(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.sweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = NewBucket(limit, now)
+		s.buckets[key] = b
+	}
+	return b.Take(limit, now)
+}
+
+// RateLimiter Middleware rejecting requests with 429 once the client's token bucket for the matched route is empty.
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset are set on every limited response, and Retry-After on
+// rejections. It should be installed with Router.Use so route rules can match.
+func RateLimiter(opts RateLimitOptions) func(http.Handler) http.Handler {
+	if opts.Key == nil {
+		opts.Key = KeyByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryBucketStore()
+	}
+	if opts.Error == nil {
+		opts.Error = http.Error
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := ""
+			if cr := mux.CurrentRoute(r); cr != nil {
+				route, _ = cr.GetPathTemplate()
+			}
+
+			limit, scope := opts.Default, "*"
+			for _, rule := range opts.Rules {
+				if (rule.Route == "" || rule.Route == route) && (rule.Method == "" || rule.Method == r.Method) {
+					limit, scope = rule.Limit, rule.Method+" "+rule.Route
+					break
+				}
+			}
+			if limit.Rate <= 0 || limit.Burst <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, remaining, wait := opts.Store.Take(opts.Key(r)+"|"+scope, limit, time.Now())
+			full := (float64(limit.Burst) - float64(remaining)) / limit.Rate
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(full))))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				opts.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
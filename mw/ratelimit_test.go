@@ -0,0 +1,102 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TestMemoryBucketStore_Take Tokens are consumed, then refilled over time up to the burst.
+func TestMemoryBucketStore_Take(t *testing.T) {
+	s := NewMemoryBucketStore()
+	limit := RateLimit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	steps := []struct {
+		at        time.Duration
+		ok        bool
+		remaining int
+	}{
+		{at: 0, ok: true, remaining: 1},
+		{at: 0, ok: true, remaining: 0},
+		{at: 0, ok: false, remaining: 0},
+		{at: time.Second, ok: true, remaining: 0},
+		{at: 10 * time.Second, ok: true, remaining: 1},
+	}
+	for n, st := range steps {
+		ok, remaining, wait := s.Take("client", limit, now.Add(st.at))
+		if ok != st.ok || remaining != st.remaining {
+			t.Errorf("step %d: got %v/%d want %v/%d", n, ok, remaining, st.ok, st.remaining)
+		}
+		if !ok && wait != time.Second {
+			t.Errorf("step %d: got wait %v want %v", n, wait, time.Second)
+		}
+	}
+}
+
+// TestRateLimiter Per-client, per-route limits with rate limit headers and 429 rejections.
+func TestRateLimiter(t *testing.T) {
+	var rejected string
+	router := mux.NewRouter()
+	router.Use(RateLimiter(RateLimitOptions{
+		Default: RateLimit{Rate: 0.001, Burst: 2},
+		Rules: []RateLimitRule{
+			{Route: "/api/resources/{id}", Method: http.MethodDelete, Limit: RateLimit{Rate: 0.001, Burst: 1}},
+			{Route: "/healthz"},
+		},
+		Key: KeyByAPIKey([]APIKey{{Hash: HashAPIKey("a")}, {Hash: HashAPIKey("b")}}),
+		Error: func(w http.ResponseWriter, err string, code int) {
+			rejected = err
+			w.WriteHeader(code)
+		},
+	}))
+	router.HandleFunc("/api/resources/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet, http.MethodDelete)
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		key       string
+		want      int
+		remaining string
+	}{
+		{name: "RateLimit - First Request", method: "GET", key: "a", want: 200, remaining: "1"},
+		{name: "RateLimit - Second Request", method: "GET", key: "a", want: 200, remaining: "0"},
+		{name: "RateLimit - Exhausted", method: "GET", key: "a", want: 429, remaining: "0"},
+		{name: "RateLimit - Other Client", method: "GET", key: "b", want: 200, remaining: "1"},
+		{name: "RateLimit - Unknown Key", method: "GET", key: "c", want: 200, remaining: "1"},
+		{name: "RateLimit - Other Unknown Key", method: "GET", key: "d", want: 200, remaining: "0"},
+		{name: "RateLimit - Unknown Keys Exhausted", method: "GET", key: "e", want: 429, remaining: "0"},
+		{name: "RateLimit - Method Rule", method: "DELETE", key: "a", want: 200, remaining: "0"},
+		{name: "RateLimit - Method Rule Exhausted", method: "DELETE", key: "a", want: 429, remaining: "0"},
+		{name: "RateLimit - Exempt Route", method: "GET", path: "/healthz", key: "a", want: 200},
+		{name: "RateLimit - Exempt Route Again", method: "GET", path: "/healthz", key: "a", want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejected = ""
+			path := tt.path
+			if path == "" {
+				path = "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497"
+			}
+			r := httptest.NewRequest(tt.method, path, nil)
+			r.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("got remaining %q want %q", got, tt.remaining)
+			}
+			if (w.Header().Get("Retry-After") != "") != (tt.want == 429) || (rejected != "") != (tt.want == 429) {
+				t.Errorf("got Retry-After %q error %q", w.Header().Get("Retry-After"), rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package mw
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader Header carrying the request id in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// ctxKey Keys of the values the middlewares of this package store in request contexts, declared together so
+// they cannot collide.
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	principalKey
+	tenantKey
+	policyKey
+)
+
+// RequestIDFrom Returns the request id stored in ctx by RequestID, or "" if there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID Accepts ids of at most 128 characters from a conservative set, so they are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID Middleware accepting a valid incoming X-Request-ID or generating one, storing it in the request
+// context and echoing it on the response before the next handler runs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
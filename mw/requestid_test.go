@@ -0,0 +1,43 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequestID Incoming ids are kept when valid and replaced otherwise.
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "RequestID - Generated", incoming: "", keep: false},
+		{name: "RequestID - Accepted", incoming: "req-42.a_b:c", keep: true},
+		{name: "RequestID - Invalid Characters Replaced", incoming: "bad id\n", keep: false},
+		{name: "RequestID - Too Long Replaced", incoming: string(make([]byte, 129)), keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFrom(r.Context())
+			}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if seen == "" || w.Header().Get(RequestIDHeader) != seen {
+				t.Errorf("got context %q header %q", seen, w.Header().Get(RequestIDHeader))
+			}
+			if (seen == tt.incoming) != tt.keep {
+				t.Errorf("got %q from incoming %q, keep %v", seen, tt.incoming, tt.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package mw
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TenantHeader Default header selecting the tenant of a request.
+const TenantHeader = "X-Tenant"
+
+// TenantFrom Returns the tenant stored in ctx by Tenant, or "" for the default namespace.
+func TenantFrom(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey).(string)
+	return t
+}
+
+// WithTenant Returns a copy of ctx scoped to tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// ValidTenant Accepts lowercase DNS labels, so tenant names are safe in hosts, paths and logs.
+func ValidTenant(t string) bool {
+	if t == "" || len(t) > 63 || t[0] == '-' || t[len(t)-1] == '-' {
+		return false
+	}
+	for _, c := range t {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// TenantOptions Configuration for Tenant.
+type TenantOptions struct {
+	// Header carries the tenant name; TenantHeader is used when empty.
+	Header string
+	// Domain enables subdomain selection: requests to <tenant>.<Domain> are scoped to tenant.
+	Domain string
+	// Error writes the 400 response for invalid names; http.Error is used when nil.
+	Error func(w http.ResponseWriter, err string, code int)
+}
+
+// Tenant Middleware storing the tenant of a request in its context. The {tenant} route variable of
+// /t/{tenant}/api/... routes wins over the header, which wins over the subdomain. Requests naming no tenant
+// use the default namespace. It must be installed with Router.Use so route variables are known.
+func Tenant(opts TenantOptions) func(http.Handler) http.Handler {
+	if opts.Header == "" {
+		opts.Header = TenantHeader
+	}
+	if opts.Error == nil {
+		opts.Error = http.Error
+	}
+	suffix := "." + strings.ToLower(strings.TrimPrefix(opts.Domain, "."))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := mux.Vars(r)["tenant"]
+			if t == "" {
+				t = r.Header.Get(opts.Header)
+			}
+			if t == "" && opts.Domain != "" {
+				host := r.Host
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+				host = strings.ToLower(host)
+				if strings.HasSuffix(host, suffix) {
+					t = strings.TrimSuffix(host, suffix)
+				}
+			}
+			if t == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !ValidTenant(t) {
+				opts.Error(w, "invalid tenant name", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), t)))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestTenant Tenant selection by path, header and subdomain.
+func TestTenant(t *testing.T) {
+	var got string
+	router := mux.NewRouter()
+	router.Use(Tenant(TenantOptions{Domain: "gorest.test"}))
+	h := func(w http.ResponseWriter, r *http.Request) { got = TenantFrom(r.Context()) }
+	router.HandleFunc("/api/resources", h)
+	router.HandleFunc("/t/{tenant}/api/resources", h)
+
+	tests := []struct {
+		name   string
+		host   string
+		path   string
+		header string
+		want   int
+		tenant string
+	}{
+		{name: "Tenant - Path Success", path: "/t/acme/api/resources", header: "other", want: 200, tenant: "acme"},
+		{name: "Tenant - Header Success", path: "/api/resources", header: "acme", want: 200, tenant: "acme"},
+		{name: "Tenant - Subdomain Success", host: "acme.gorest.test:8181", path: "/api/resources", want: 200, tenant: "acme"},
+		{name: "Tenant - Default Success", host: "gorest.test", path: "/api/resources", want: 200},
+		{name: "Tenant - Invalid Name Failure", path: "/api/resources", header: "Acme_Corp", want: 400},
+		{name: "Tenant - Nested Subdomain Failure", host: "a.b.gorest.test", path: "/api/resources", want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			if tt.header != "" {
+				r.Header.Set(TenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got %d want %d", w.Code, tt.want)
+			}
+			if got != tt.tenant {
+				t.Errorf("got tenant %q want %q", got, tt.tenant)
+			}
+		})
+	}
+}
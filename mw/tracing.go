@@ -0,0 +1,58 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName Instrumentation name used for spans started by this package.
+const TracerName = "github.com/angarcia/gorest/mw"
+
+// Tracing Middleware starting a server span per request, continuing any W3C traceparent sent by the caller.
+// Spans are named after the gorilla/mux route template, so it should be installed with Router.Use.
+func Tracing(tp trace.TracerProvider) func(http.Handler) http.Handler {
+	tracer := tp.Tracer(TracerName)
+	prop := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+
+			ctx := prop.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.String("http.target", r.URL.RequestURI()),
+					attribute.String("http.user_agent", r.UserAgent()),
+				),
+			)
+			defer span.End()
+			if id := RequestIDFrom(ctx); id != "" {
+				span.SetAttributes(attribute.String("http.request_id", id))
+			}
+
+			rr := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rr, r.WithContext(ctx))
+			if rr.status == 0 {
+				rr.status = http.StatusOK
+			}
+
+			span.SetAttributes(attribute.Int("http.status_code", rr.status))
+			if rr.status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(rr.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTracing Server spans are named by route and continue an incoming traceparent.
+func TestTracing(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		status      int
+		parent      string
+		failed      bool
+	}{
+		{name: "Tracing - New Trace", status: http.StatusOK},
+		{
+			name:        "Tracing - Continued Trace",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			status:      http.StatusOK,
+			parent:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{name: "Tracing - Server Error", status: http.StatusInternalServerError, failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			router := mux.NewRouter()
+			router.Use(Tracing(tp))
+			router.HandleFunc("/api/resources/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if !trace.SpanContextFromContext(r.Context()).IsValid() {
+					t.Errorf("handler context carries no span")
+				}
+				w.WriteHeader(tt.status)
+			})
+
+			r := httptest.NewRequest("GET", "/api/resources/0bf8651a-0923-47b8-aed3-e9fc1505e497", nil)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans want %d", len(spans), 1)
+			}
+			s := spans[0]
+			if s.Name() != "GET /api/resources/{id}" || s.SpanKind() != trace.SpanKindServer {
+				t.Errorf("got span %q kind %v", s.Name(), s.SpanKind())
+			}
+			if tt.parent != "" && s.SpanContext().TraceID().String() != tt.parent {
+				t.Errorf("got trace %s want %s", s.SpanContext().TraceID(), tt.parent)
+			}
+			if failed := s.Status().Code.String() == "Error"; failed != tt.failed {
+				t.Errorf("got failed %v want %v", failed, tt.failed)
+			}
+		})
+	}
+}
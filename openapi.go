@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/angarcia/gorest/openapi"
+	"github.com/gorilla/mux"
+)
+
+// tenantPrefix Prefix of the tenant-scoped copy of the API, documented like the unscoped routes.
+const tenantPrefix = "/t/{tenant}"
+
+// ttlHeader Sets the expiry of a created or replaced resource.
+var ttlHeader = openapi.Parameter{Name: handlers.TTLHeader, In: "header",
+	Description: "expire the resource after this Go duration or number of seconds", Schema: openapi.Schema{"type": "string"}}
+
+// apiDocs Documentation of every route, keyed by method and path template without the tenant prefix.
+var apiDocs = map[string]openapi.Route{
+	"GET /livez":        {Summary: "Liveness probe", Tags: []string{"health"}, Response: "HealthStatus"},
+	"GET /healthz":      {Summary: "Run every health check", Tags: []string{"health"}, Response: "HealthStatus"},
+	"GET /readyz":       {Summary: "Readiness probe, failing while starting or shutting down", Tags: []string{"health"}, Response: "HealthStatus"},
+	"GET /metrics":      {Summary: "Prometheus metrics", Tags: []string{"health"}, Response: "Text", MediaType: "text/plain"},
+	"GET /openapi.json": {Summary: "This OpenAPI document", Tags: []string{"docs"}, Response: "Any"},
+
+	"GET /api/resources/events": {Summary: "Stream resource changes as server-sent events", Tags: []string{"events"},
+		Params: []openapi.Parameter{{Name: "document", In: "query", Description: "include the changed document", Schema: openapi.Schema{"type": "boolean"}},
+			{Name: "Last-Event-ID", In: "header", Description: "resume after this event; without it only new events are sent", Schema: openapi.Schema{"type": "integer"}}},
+		Response: "Event", MediaType: "text/event-stream"},
+	"GET /api/resources/ws": {Summary: "Subscribe to resource changes over a WebSocket", Tags: []string{"events"}, Status: http.StatusSwitchingProtocols},
+	"GET /api/resources": {Summary: "List resources, a page at a time with limit", Tags: []string{"resources"},
+		Params: []openapi.Parameter{{Name: "filter", In: "query", Description: `comparisons joined by and, e.g. name == "Bruce" and age >= 30`, Schema: openapi.Schema{"type": "string"}},
+			{Name: "limit", In: "query", Description: "page size; the Link header points to the next page", Schema: openapi.Schema{"type": "integer", "minimum": 1}},
+			{Name: "after", In: "query", Description: "return resources with ids after this one", Schema: openapi.Schema{"type": "string"}}},
+		Response: "Resources"},
+	"POST /api/resources": {Summary: "Create a resource", Tags: []string{"resources"}, Params: []openapi.Parameter{ttlHeader},
+		Request: "Resource", Status: http.StatusCreated, Response: "Resource"},
+	"GET /api/resources/{id}": {Summary: "Get a resource, or one of its revisions", Tags: []string{"resources"},
+		Params: []openapi.Parameter{{Name: "version", In: "query", Description: "read this revision", Schema: openapi.Schema{"type": "integer"}},
+			{Name: "asOf", In: "query", Description: "read the revision current at this RFC 3339 time", Schema: openapi.Schema{"type": "string", "format": "date-time"}}},
+		Response: "Resource"},
+	"PUT /api/resources/{id}": {Summary: "Replace a resource", Tags: []string{"resources"}, Params: []openapi.Parameter{ttlHeader},
+		Request: "Resource", Status: http.StatusAccepted, Response: "Resource"},
+	"PATCH /api/resources/{id}": {Summary: "Apply a JSON merge patch to a resource", Tags: []string{"resources"}, Params: []openapi.Parameter{ttlHeader},
+		Request: "Resource", Response: "Resource"},
+	"POST /api/transactions": {Summary: "Apply operations on several resources atomically, rolling back on the first failure", Tags: []string{"resources"},
+		Request: "Transaction", Response: "TxResult"},
+	"DELETE /api/resources/{id}":        {Summary: "Delete a resource, into the trash with soft delete", Tags: []string{"resources"}, Status: http.StatusNoContent},
+	"GET /api/resources/{id}/history":   {Summary: "List the retained revisions of a resource", Tags: []string{"history"}, Response: "Revisions"},
+	"POST /api/resources/{id}/restore":  {Summary: "Restore a revision of a live or trashed resource as its current document", Tags: []string{"history"}, Params: []openapi.Parameter{{Name: "version", In: "query", Required: true, Schema: openapi.Schema{"type": "integer"}}}, Response: "Resource"},
+	"GET /api/trash":                    {Summary: "List trashed resources", Tags: []string{"trash"}, Response: "Trash"},
+	"DELETE /api/trash/{id}":            {Summary: "Purge a trashed resource", Tags: []string{"trash"}, Status: http.StatusNoContent},
+	"POST /api/trash/{id}/restore":      {Summary: "Restore a trashed resource", Tags: []string{"trash"}, Status: http.StatusCreated, Response: "Resource"},
+	"GET /api/webhooks":                 {Summary: "List webhooks", Tags: []string{"webhooks"}, Response: "Webhooks"},
+	"POST /api/webhooks":                {Summary: "Register a webhook", Tags: []string{"webhooks"}, Request: "Webhook", Status: http.StatusCreated, Response: "Webhook"},
+	"GET /api/webhooks/dead-letters":    {Summary: "List deliveries that exhausted their retries", Tags: []string{"webhooks"}, Response: "Deliveries"},
+	"GET /api/webhooks/{id}":            {Summary: "Get a webhook", Tags: []string{"webhooks"}, Response: "Webhook"},
+	"DELETE /api/webhooks/{id}":         {Summary: "Remove a webhook", Tags: []string{"webhooks"}, Status: http.StatusNoContent},
+	"GET /api/webhooks/{id}/deliveries": {Summary: "List recent deliveries of a webhook", Tags: []string{"webhooks"}, Response: "Deliveries"},
+	"GET /api/tenants":                  {Summary: "List tenants", Tags: []string{"tenants"}, Response: "Tenants"},
+	"POST /api/tenants":                 {Summary: "Create a tenant, which must exist before writes to it", Tags: []string{"tenants"}, Request: "Tenant", Status: http.StatusCreated, Response: "Tenant"},
+	"DELETE /api/tenants/{name}":        {Summary: "Delete a tenant and its store", Tags: []string{"tenants"}, Status: http.StatusNoContent},
+}
+
+// docs Looks a route up in apiDocs.
+func docs(method, path string) (openapi.Route, bool) {
+	rd, ok := apiDocs[method+" "+strings.TrimPrefix(path, tenantPrefix)]
+	return rd, ok
+}
+
+// schemas Returns the component schemas; resources are described by the JSON Schema at resourceSchema when set.
+func schemas(resourceSchema string) (map[string]openapi.Schema, error) {
+	resource := openapi.Schema{"type": "object", "additionalProperties": true}
+	if resourceSchema != "" {
+		b, err := os.ReadFile(resourceSchema)
+		if err != nil {
+			return nil, err
+		}
+		resource = openapi.Schema{}
+		if err := json.Unmarshal(b, &resource); err != nil {
+			return nil, fmt.Errorf("%s: %w", resourceSchema, err)
+		}
+	}
+
+	return map[string]openapi.Schema{
+		"Any":          {},
+		"Text":         {"type": "string"},
+		"Error":        openapi.SchemaOf(handlers.ErrorHttp{}),
+		"HealthStatus": openapi.SchemaOf(handlers.HealthStatus{}),
+		"Resource":     resource,
+		"Resources":    {"type": "object", "additionalProperties": openapi.Ref("Resource")},
+		"Event":        openapi.SchemaOf(handlers.Event{}),
+		"Revisions":    {"type": "array", "items": openapi.SchemaOf(handlers.Revision{})},
+		"Transaction":  openapi.SchemaOf(handlers.Transaction{}),
+		"TxResult":     openapi.SchemaOf(handlers.TransactionResult{}),
+		"Trash":        {"type": "object", "additionalProperties": openapi.SchemaOf(handlers.Trashed{})},
+		"Webhook":      openapi.SchemaOf(handlers.Webhook{}),
+		"Webhooks":     {"type": "array", "items": openapi.Ref("Webhook")},
+		"Deliveries":   {"type": "array", "items": openapi.SchemaOf(handlers.Delivery{})},
+		"Tenant":       openapi.SchemaOf(handlers.Tenant{}),
+		"Tenants":      {"type": "array", "items": openapi.Ref("Tenant")},
+	}, nil
+}
+
+// spec Generates the JSON document of every route on router, failing on routes missing from apiDocs.
+func spec(router *mux.Router, resourceSchema string) ([]byte, error) {
+	s, err := schemas(resourceSchema)
+	if err != nil {
+		return nil, err
+	}
+	info := openapi.Info{Title: "gorest", Description: "In-memory REST resource server.", Version: "1.0.0"}
+	doc, err := openapi.Generate(router, info, docs, s)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,202 @@
+// Package openapi generates an OpenAPI 3 document from the routes registered on a gorilla/mux router, described
+// by per-route documentation looked up by method and path template.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Version OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// Schema A JSON Schema object, kept as a map so schemas read from files can be embedded unchanged.
+type Schema map[string]interface{}
+
+// Ref Returns a schema referring to the component schema called name.
+func Ref(name string) Schema {
+	return Schema{"$ref": "#/components/schemas/" + name}
+}
+
+// Document OpenAPI document root.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info API metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem Operations of one path keyed by lower-case method.
+type PathItem map[string]*Operation
+
+// Operation One method on one path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter A path, query or header parameter.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// RequestBody Body accepted by an operation.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType Schema of a body in one media type.
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Response One response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components Reusable schemas.
+type Components struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// Route Documentation of one route.
+type Route struct {
+	Summary string
+	Tags    []string
+	// Params lists the query and header parameters; path parameters are taken from the template.
+	Params []Parameter
+	// Request names the component schema of the JSON request body, if any.
+	Request string
+	// Status is the success status, 200 when zero.
+	Status int
+	// Response names the component schema of the success body; empty for no body.
+	Response string
+	// MediaType of the success body, application/json when empty.
+	MediaType string
+}
+
+// Docs Returns the documentation of the route with the given method and path template.
+type Docs func(method, path string) (Route, bool)
+
+// params Matches path template variables, with an optional pattern: {id} or {id:[0-9]+}.
+var params = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*)?\}`)
+
+// Generate Builds the document for every route of router that is restricted to methods, looked up in docs.
+// Routes missing from docs are reported as an error, so the document cannot silently fall out of sync.
+// Responses other than the success status refer to the "Error" component schema when schemas has one.
+func Generate(router *mux.Router, info Info, docs Docs, schemas map[string]Schema) (*Document, error) {
+	doc := &Document{OpenAPI: Version, Info: info, Paths: make(map[string]PathItem), Components: Components{Schemas: schemas}}
+	var undocumented []string
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			rd, ok := docs(method, tmpl)
+			if !ok {
+				undocumented = append(undocumented, method+" "+tmpl)
+				continue
+			}
+			path := params.ReplaceAllString(tmpl, "{$1}")
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = PathItem{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = operation(method, path, rd, schemas)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("openapi: undocumented routes: %s", strings.Join(undocumented, ", "))
+	}
+	return doc, nil
+}
+
+// operation Builds the operation for rd on path.
+func operation(method, path string, rd Route, schemas map[string]Schema) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     rd.Summary,
+		Tags:        rd.Tags,
+		Responses:   make(map[string]Response),
+	}
+	for _, m := range params.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: Schema{"type": "string"}})
+	}
+	op.Parameters = append(op.Parameters, rd.Params...)
+
+	if rd.Request != "" {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: Ref(rd.Request)}}}
+	}
+
+	status := rd.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := Response{Description: http.StatusText(status)}
+	if rd.Response != "" {
+		mt := rd.MediaType
+		if mt == "" {
+			mt = "application/json"
+		}
+		res.Content = map[string]MediaType{mt: {Schema: Ref(rd.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = res
+
+	if _, ok := schemas["Error"]; ok {
+		op.Responses["default"] = Response{Description: "Error", Content: map[string]MediaType{"application/json": {Schema: Ref("Error")}}}
+	}
+	return op
+}
+
+// operationID Derives a unique id from method and path, e.g. getResourcesById for GET /api/resources/{id}.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" || seg == "api" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") {
+			b.WriteString("By")
+			seg = strings.Trim(seg, "{}")
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '.' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TestGenerate Operations, path parameters and undocumented routes.
+func TestGenerate(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/items/{id:[0-9]+}", http.NotFound).Methods(http.MethodGet, http.MethodDelete)
+	router.HandleFunc("/api/items", http.NotFound).Methods(http.MethodPost)
+	router.PathPrefix("/static/").Handler(http.NotFoundHandler())
+
+	docs := map[string]Route{
+		"GET /api/items/{id:[0-9]+}":    {Summary: "Get", Response: "Item"},
+		"DELETE /api/items/{id:[0-9]+}": {Status: http.StatusNoContent},
+		"POST /api/items":               {Request: "Item", Status: http.StatusCreated, Response: "Item"},
+	}
+	lookup := func(method, path string) (Route, bool) {
+		rd, ok := docs[method+" "+path]
+		return rd, ok
+	}
+	schemas := map[string]Schema{"Item": {"type": "object"}, "Error": {"type": "object"}}
+
+	doc, err := Generate(router, Info{Title: "test", Version: "1"}, lookup, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("got paths %v want 2", doc.Paths)
+	}
+	get := doc.Paths["/api/items/{id}"]["get"]
+	if get == nil || get.OperationID != "getItemsById" || len(get.Parameters) != 1 || get.Parameters[0].Name != "id" {
+		t.Fatalf("got %+v want getItemsById with an id parameter", get)
+	}
+	if _, ok := get.Responses["default"]; !ok {
+		t.Errorf("missing default error response")
+	}
+	post := doc.Paths["/api/items"]["post"]
+	if post == nil || post.RequestBody == nil || post.Responses["201"].Content["application/json"].Schema["$ref"] != "#/components/schemas/Item" {
+		t.Errorf("got %+v want request body and 201 Item response", post)
+	}
+	if res := doc.Paths["/api/items/{id}"]["delete"].Responses["204"]; res.Content != nil {
+		t.Errorf("got %+v want 204 without content", res)
+	}
+
+	delete(docs, "POST /api/items")
+	if _, err := Generate(router, Info{}, lookup, schemas); err == nil || !strings.Contains(err.Error(), "POST /api/items") {
+		t.Errorf("got %v want undocumented route error", err)
+	}
+}
+
+// TestSchemaOf JSON Schemas follow encoding/json field rules.
+func TestSchemaOf(t *testing.T) {
+	type item struct {
+		ID      string            `json:"id"`
+		Count   int               `json:"count,omitempty"`
+		Tags    []string          `json:"tags"`
+		Meta    map[string]string `json:"meta,omitempty"`
+		Created time.Time         `json:"created"`
+		Expires *time.Time        `json:"expires,omitempty"`
+		Secret  string            `json:"-"`
+		hidden  bool
+	}
+
+	s := SchemaOf(item{})
+	props := s["properties"].(Schema)
+	if len(props) != 6 {
+		t.Errorf("got properties %v want 6", props)
+	}
+	if !reflect.DeepEqual(s["required"], []string{"id", "tags", "created"}) {
+		t.Errorf("got required %v", s["required"])
+	}
+	if props["created"].(Schema)["format"] != "date-time" || props["expires"].(Schema)["nullable"] != true {
+		t.Errorf("got time schemas %v %v", props["created"], props["expires"])
+	}
+	if props["tags"].(Schema)["type"] != "array" || props["meta"].(Schema)["type"] != "object" {
+		t.Errorf("got %v %v", props["tags"], props["meta"])
+	}
+}
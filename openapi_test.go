@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/gorilla/mux"
+)
+
+// testRouter Registers every route the server can serve, with metrics and tenancy enabled.
+func testRouter() *mux.Router {
+	router := mux.NewRouter()
+	rh := handlers.CreateHandler(make(map[string]map[string]interface{}))
+	wh := handlers.CreateWebhookHandler(handlers.CreateWebhookDispatcher(rh.Events(), 1))
+	router.Handle("/metrics", http.NotFoundHandler()).Methods(http.MethodGet)
+	router.Handle("/openapi.json", http.NotFoundHandler()).Methods(http.MethodGet)
+	apis := []*mux.Router{router.PathPrefix("/api/").Subrouter(), router.PathPrefix("/t/{tenant}/api/").Subrouter()}
+	routes(router, apis, rh, wh, handlers.CreateHealthHandler(), true)
+	return router
+}
+
+// TestSpec The OpenAPI document covers every route and documents no route that does not exist.
+func TestSpec(t *testing.T) {
+	router := testRouter()
+	data, err := spec(router, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for key := range apiDocs {
+		method, path, _ := strings.Cut(key, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("documented route %s is not registered", key)
+		}
+		if strings.HasPrefix(path, "/api/") && path != "/api/tenants" && !strings.HasPrefix(path, "/api/tenants/") {
+			if _, ok := doc.Paths[tenantPrefix+path][strings.ToLower(method)]; !ok {
+				t.Errorf("documented route %s is not registered under %s", key, tenantPrefix)
+			}
+		}
+	}
+
+	router.HandleFunc("/api/undocumented", http.NotFound).Methods(http.MethodGet)
+	if _, err := spec(router, ""); err == nil || !strings.Contains(err.Error(), "GET /api/undocumented") {
+		t.Errorf("got %v want undocumented route error", err)
+	}
+}
+
+// TestSpec_ResourceSchema An attached JSON Schema describes resource documents.
+func TestSpec_ResourceSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resource.json")
+	os.WriteFile(path, []byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}`), 0o600)
+
+	data, err := spec(testRouter(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Resource":{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"}`) {
+		t.Errorf("resource schema missing from %s", data)
+	}
+
+	os.WriteFile(path, []byte(`{`), 0o600)
+	if _, err := spec(testRouter(), path); err == nil {
+		t.Errorf("got nil error for invalid schema")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/angarcia/gorest/handlers"
+	"github.com/gorilla/mux"
+)
+
+// routes Registers the probes on router and the API on every prefix in apis, the first of which also carries the
+// tenant administration routes when tenancy is enabled. Every route must be documented in apiDocs.
+func routes(router *mux.Router, apis []*mux.Router, rh *handlers.ResourceHandler, wh *handlers.WebhookHandler, hh *handlers.HealthHandler, tenancy bool) {
+	router.HandleFunc("/livez", hh.GetLiveHandler).Methods(http.MethodGet)
+	router.HandleFunc("/healthz", hh.GetHealthHandler).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", hh.GetReadyHandler).Methods(http.MethodGet)
+
+	for _, api := range apis {
+		rh.Routes(api)
+		api.HandleFunc("/webhooks", wh.GetWebhooksHandler).Methods(http.MethodGet)
+		api.HandleFunc("/webhooks", wh.CreateWebhookHandler).Methods(http.MethodPost)
+		api.HandleFunc("/webhooks/dead-letters", wh.GetDeadLettersHandler).Methods(http.MethodGet)
+		api.HandleFunc("/webhooks/{id}", wh.GetWebhookHandler).Methods(http.MethodGet)
+		api.HandleFunc("/webhooks/{id}", wh.DeleteWebhookHandler).Methods(http.MethodDelete)
+		api.HandleFunc("/webhooks/{id}/deliveries", wh.GetDeliveriesHandler).Methods(http.MethodGet)
+	}
+	if tenancy {
+		apis[0].HandleFunc("/tenants", rh.GetTenantsHandler).Methods(http.MethodGet)
+		apis[0].HandleFunc("/tenants", rh.CreateTenantHandler).Methods(http.MethodPost)
+		apis[0].HandleFunc("/tenants/{name}", rh.DeleteTenantHandler).Methods(http.MethodDelete)
+	}
+}
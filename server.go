@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/angarcia/gorest/handlers"
+)
+
+// serve Runs srv until ctx is cancelled, then shuts it down gracefully: readiness turns false, requests keep being
+// served for delay so load balancers stop routing, then the listener closes and in-flight requests get up to
+// timeout to finish before their connections are cut.
+func serve(ctx context.Context, srv *http.Server, hh *handlers.HealthHandler, delay, timeout time.Duration) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate, so no files are passed.
+			errc <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+	hh.SetReady()
+	log.Printf("Starting Server: '%s'", ln.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	hh.SetNotReady("shutting down")
+	log.Printf("Shutting Down: not ready, draining in %v", delay)
+	time.Sleep(delay)
+
+	sctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutdown: in-flight requests cut after %v: %w", timeout, err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Printf("Server Stopped")
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/angarcia/gorest/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newTracerProvider Builds the tracer provider for the selected exporter: "none", "file" (OTLP/JSON appended to
+// cfg.File) or "otlp" (OTLP over HTTP to cfg.Endpoint, e.g. localhost:4318, in plain text when cfg.Insecure).
+// The returned function flushes and stops it, then syncs and closes the span file.
+func newTracerProvider(cfg config.Trace) (trace.TracerProvider, func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+	var f *os.File
+	switch cfg.Exporter {
+	case "", "none":
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case "file":
+		var err error
+		if f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return nil, nil, err
+		}
+		if exp, err = otlptrace.New(context.Background(), &otlpFile{f: f}); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		var err error
+		if exp, err = otlptracehttp.New(context.Background(), opts...); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, errors.New("trace exporter must be none, file or otlp")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "gorest"))),
+	)
+	if f == nil {
+		return tp, tp.Shutdown, nil
+	}
+	return tp, func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if serr := f.Sync(); err == nil {
+			err = serr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// otlpFile otlptrace.Client appending each batch to a file as one OTLP/JSON ExportTraceServiceRequest per line,
+// the format read by the OpenTelemetry Collector's otlpjsonfile receiver.
+type otlpFile struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Start Implements otlptrace.Client.
+func (c *otlpFile) Start(context.Context) error {
+	return nil
+}
+
+// Stop Implements otlptrace.Client. The file is closed by the tracer provider's shutdown function.
+func (c *otlpFile) Stop(context.Context) error {
+	return nil
+}
+
+// UploadTraces Implements otlptrace.Client.
+func (c *otlpFile) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	b, err := protojson.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.f.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angarcia/gorest/config"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TestNewTracerProvider_File Spans still batched at shutdown reach the file as OTLP/JSON, and the file is closed
+// afterwards.
+func TestNewTracerProvider_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	tp, shutdown, err := newTracerProvider(config.Trace{Exporter: "file", File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tp.Tracer("test").Start(context.Background(), "last-span")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := protojson.Unmarshal(bytes.TrimSpace(b), &req); err != nil {
+		t.Fatalf("got %s, %v want an OTLP/JSON ExportTraceServiceRequest", b, err)
+	}
+	if name := req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name; name != "last-span" {
+		t.Errorf("got span %q want %q", name, "last-span")
+	}
+	if err := shutdown(context.Background()); err == nil {
+		t.Errorf("got nil error want the file already closed")
+	}
+}